@@ -1,13 +1,46 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 
 	"github.com/fredrikmwold/rebasei-tui/internal/ui"
 )
 
 func main() {
-	if err := ui.Run(); err != nil {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] [<upstream>|<commit>|<git-rebase-todo>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s undo\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Rebase the commits since <upstream> (default: the branch's configured upstream).")
+		fmt.Fprintln(os.Stderr, "When given a git-rebase-todo file, edit it as git's sequence editor:")
+		fmt.Fprintln(os.Stderr, "  GIT_SEQUENCE_EDITOR=rebasei-tui git rebase -i origin/main")
+		fmt.Fprintln(os.Stderr, "\"undo\" restores a branch from the backup taken before an earlier rebase.")
+		flag.PrintDefaults()
+	}
+	var opts ui.Options
+	flag.StringVar(&opts.Dir, "C", "", "run as if started in `path`, like git -C")
+	flag.BoolVar(&opts.RebaseMerges, "rebase-merges", false, "keep merge commits, recreating them with label/reset/merge")
+	flag.BoolVar(&opts.RebaseMerges, "r", false, "shorthand for -rebase-merges")
+	flag.BoolVar(&opts.UpdateRefs, "update-refs", false, "move stacked branches along with the rewritten commits")
+	flag.IntVar(&opts.Count, "n", 20, "number of commits to load up front and per page as you scroll")
+	flag.BoolVar(&opts.Sign, "S", false, "sign the rewritten commits, like git rebase -S (default: commit.gpgSign)")
+	flag.BoolVar(&opts.InPlace, "in-place", false, "always run git rebase in the working tree, even when the plan could be rewritten in memory")
+	flag.Parse()
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts.Base = flag.Arg(0)
+	// git invokes its sequence editor with the path to the todo file.
+	if filepath.Base(opts.Base) == "git-rebase-todo" {
+		opts = ui.Options{TodoFile: opts.Base, Dir: opts.Dir}
+	} else if opts.Base == "undo" {
+		opts = ui.Options{Undo: true, Dir: opts.Dir}
+	}
+	if err := ui.Run(opts); err != nil {
 		log.Fatal(err)
 	}
 }
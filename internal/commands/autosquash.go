@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+// fixupPrefixes maps the subject markers git commit --fixup/--squash create
+// to the action and option they imply.
+var fixupPrefixes = []struct {
+	prefix string
+	cmd    todo.Command
+	option string
+}{
+	{"fixup! ", todo.Fixup, ""},
+	{"amend! ", todo.Fixup, "-C"},
+	{"squash! ", todo.Squash, ""},
+}
+
+// Autosquash rearranges a plan of commit rows (newest first) the way
+// "git rebase --autosquash" does: every commit whose subject starts with
+// "fixup! ", "squash! " or "amend! " is moved right after its target and
+// gets the matching action. Targets are found by subject, by hash prefix or
+// by subject prefix, and must be older than the marked commit. Dropped
+// commits are left alone. It returns the new plan and the number of commits
+// that were moved.
+func Autosquash(plan []CommitAction) ([]CommitAction, int) {
+	// Work oldest first, like the todo itself.
+	n := len(plan)
+	chrono := make([]CommitAction, n)
+	for i, ca := range plan {
+		chrono[n-1-i] = ca
+	}
+	bySubject := map[string]int{}
+	followers := make([][]int, n) // target -> marked commits, in order
+	moved := make([]bool, n)
+	count := 0
+	for i, ca := range chrono {
+		if ca.Instruction.Command == todo.Drop {
+			continue
+		}
+		subject := ca.Commit.Subject
+		if cmd, option, target, ok := parseFixupSubject(subject); ok {
+			if j := findTarget(chrono[:i], bySubject, target); j >= 0 {
+				// Chain onto the root target so fixups of fixups stay together.
+				for moved[j] {
+					j = rootOf(followers, j)
+				}
+				ca.Instruction.Command, ca.Instruction.Option = cmd, option
+				chrono[i] = ca
+				followers[j] = append(followers[j], i)
+				moved[i] = true
+				count++
+			}
+		}
+		if _, ok := bySubject[subject]; !ok {
+			bySubject[subject] = i
+		}
+	}
+	if count == 0 {
+		return plan, 0
+	}
+	res := make([]CommitAction, 0, n)
+	for i, ca := range chrono {
+		if moved[i] {
+			continue
+		}
+		res = append(res, ca)
+		for _, f := range followers[i] {
+			res = append(res, chrono[f])
+		}
+	}
+	// Back to newest first.
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+	return res, count
+}
+
+// parseFixupSubject strips all fixup/squash/amend markers from subject and
+// returns the action implied by the first one and the remaining target.
+func parseFixupSubject(subject string) (todo.Command, string, string, bool) {
+	var cmd todo.Command
+	var option string
+	found := false
+	for {
+		matched := false
+		for _, p := range fixupPrefixes {
+			if rest, ok := strings.CutPrefix(subject, p.prefix); ok {
+				if !found {
+					cmd, option, found = p.cmd, p.option, true
+				}
+				subject = strings.TrimLeft(rest, " ")
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return cmd, option, subject, found
+		}
+	}
+}
+
+// findTarget returns the index of the commit target refers to among older,
+// or -1.
+func findTarget(older []CommitAction, bySubject map[string]int, target string) int {
+	if j, ok := bySubject[target]; ok {
+		return j
+	}
+	if !strings.Contains(target, " ") && len(target) >= 4 {
+		for j, ca := range older {
+			if ca.Instruction.Command != todo.Drop && strings.HasPrefix(ca.Commit.Hash, target) {
+				return j
+			}
+		}
+	}
+	for j, ca := range older {
+		if ca.Instruction.Command != todo.Drop && strings.HasPrefix(ca.Commit.Subject, target) {
+			return j
+		}
+	}
+	return -1
+}
+
+// rootOf returns the commit whose followers include i.
+func rootOf(followers [][]int, i int) int {
+	for j, fs := range followers {
+		for _, f := range fs {
+			if f == i {
+				return j
+			}
+		}
+	}
+	return i
+}
@@ -0,0 +1,90 @@
+package commands_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+// pickPlan returns a plan picking commits with the given subjects, given
+// oldest first like a history is written; the plan is newest first. The
+// hash of the i-th commit is "c0ffee<i>" followed by zeros.
+func pickPlan(subjects ...string) []commands.CommitAction {
+	plan := make([]commands.CommitAction, len(subjects))
+	for i, s := range subjects {
+		hash := fmt.Sprintf("c0ffee%d%033d", i, 0)
+		plan[len(subjects)-1-i] = commands.CommitAction{
+			Commit:      commands.Commit{Hash: hash, Subject: s},
+			Instruction: todo.Instruction{Command: todo.Pick, Commit: hash, Text: s},
+		}
+	}
+	return plan
+}
+
+func TestAutosquash(t *testing.T) {
+	tests := []struct {
+		name  string
+		plan  []commands.CommitAction
+		want  []string
+		moved int
+	}{
+		{
+			name: "nothing to arrange",
+			plan: pickPlan("A", "B"),
+			want: []string{"pick A", "pick B"},
+		},
+		{
+			name:  "markers",
+			plan:  pickPlan("A", "B", "fixup! A", "squash! A", "amend! B"),
+			want:  []string{"pick A", "fixup fixup! A", "squash squash! A", "pick B", "fixup -C amend! B"},
+			moved: 3,
+		},
+		{
+			name:  "fixup of a fixup follows the root target",
+			plan:  pickPlan("A", "B", "fixup! A", "fixup! fixup! A"),
+			want:  []string{"pick A", "fixup fixup! A", "fixup fixup! fixup! A", "pick B"},
+			moved: 2,
+		},
+		{
+			name:  "hash and subject prefix",
+			plan:  pickPlan("Add the parser", "B", "fixup! c0ffee0", "squash! Add the"),
+			want:  []string{"pick Add the parser", "fixup fixup! c0ffee0", "squash squash! Add the", "pick B"},
+			moved: 2,
+		},
+		{
+			name: "target must be older",
+			plan: pickPlan("fixup! A", "A"),
+			want: []string{"pick fixup! A", "pick A"},
+		},
+		{
+			name: "unknown target",
+			plan: pickPlan("A", "fixup! Z"),
+			want: []string{"pick A", "pick fixup! Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, moved := commands.Autosquash(tt.plan)
+			if moved != tt.moved {
+				t.Errorf("moved = %d, want %d", moved, tt.moved)
+			}
+			if r := rows(got); !reflect.DeepEqual(r, tt.want) {
+				t.Errorf("Autosquash =\n%q\nwant\n%q", r, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutosquashSkipsDropped(t *testing.T) {
+	plan := pickPlan("A", "fixup! A", "fixup! A")
+	// The older fixup is dropped; the newer one still finds A.
+	plan[1].Instruction.Command = todo.Drop
+	got, moved := commands.Autosquash(plan)
+	want := []string{"pick A", "fixup fixup! A", "drop fixup! A"}
+	if r := rows(got); moved != 1 || !reflect.DeepEqual(r, want) {
+		t.Errorf("Autosquash = %q, %d moved, want %q, 1 moved", r, moved, want)
+	}
+}
@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// backupRefPrefix is where backups of rebased branches are kept.
+const backupRefPrefix = "refs/rebasei/backup/"
+
+// Backup records where a branch was before a rebase rewrote it.
+type Backup struct {
+	// Ref is the backup ref, refs/rebasei/backup/<timestamp>.
+	Ref string
+	// Branch is the full name of the rebased branch, or "" for a detached HEAD.
+	Branch string
+	// Head is the full hash the branch pointed at.
+	Head string
+	// Summary is a one-line description of the plan, e.g. "3 pick, 1 drop".
+	Summary string
+	// Plan is the todo that was run, oldest first.
+	Plan string
+	Time time.Time
+}
+
+// ShortBranch returns the branch without refs/heads/, or "detached HEAD".
+func (b Backup) ShortBranch() string {
+	if b.Branch == "" {
+		return "detached HEAD"
+	}
+	return strings.TrimPrefix(b.Branch, "refs/heads/")
+}
+
+// CreateBackup records head (and branch, a full ref name or "") under a new
+// backup ref before a rebase rewrites them. The ref points at a commit made
+// with commit-tree whose parent is head and whose message holds the branch
+// and the plan, so nothing about it depends on the reflog.
+func CreateBackup(ctx context.Context, head, branch string, plan []CommitAction) (Backup, error) {
+	head, err := revParse(ctx, head+"^{commit}")
+	if err != nil {
+		return Backup{}, err
+	}
+	b := Backup{Branch: branch, Head: head, Summary: planSummary(plan), Time: time.Now()}
+	var todoText strings.Builder
+	for _, ins := range instructions(plan, "") {
+		todoText.WriteString(ins.String() + "\n")
+	}
+	b.Plan = todoText.String()
+	msg := fmt.Sprintf("rebasei backup of %s\n\nbranch: %s\nhead: %s\nsummary: %s\n\n%s", b.ShortBranch(), branch, head, b.Summary, b.Plan)
+	out, err := gitContext(ctx, "commit-tree", "-p", head, "-m", msg, head+"^{tree}")
+	if err != nil {
+		return Backup{}, err
+	}
+	obj := strings.TrimSpace(string(out))
+	stamp := b.Time.UTC().Format("20060102-150405")
+	for i := 1; ; i++ {
+		b.Ref = backupRefPrefix + stamp
+		if i > 1 {
+			b.Ref += fmt.Sprintf("-%d", i)
+		}
+		// An empty old value makes update-ref fail if the ref already exists.
+		if _, err := gitContext(ctx, "update-ref", "-m", "rebasei: backup", b.Ref, obj, ""); err == nil {
+			return b, nil
+		} else if i >= 10 {
+			return Backup{}, err
+		}
+	}
+}
+
+// ListBackups returns the recorded backups, newest first.
+func ListBackups(ctx context.Context) ([]Backup, error) {
+	out, err := gitContext(ctx, "for-each-ref", "--sort=-creatordate",
+		"--format=%(refname)%00%(creatordate:unix)%00%(contents)%1e", backupRefPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var res []Backup
+	for _, rec := range strings.Split(string(out), "\x1e") {
+		f := strings.SplitN(strings.TrimLeft(rec, "\n"), "\x00", 3)
+		if len(f) != 3 {
+			continue
+		}
+		b := Backup{Ref: f[0]}
+		var unix int64
+		fmt.Sscan(f[1], &unix)
+		b.Time = time.Unix(unix, 0)
+		_, body, _ := strings.Cut(f[2], "\n\n")
+		header, plan, _ := strings.Cut(body, "\n\n")
+		b.Plan = plan
+		for _, l := range strings.Split(header, "\n") {
+			k, v, _ := strings.Cut(l, ": ")
+			switch k {
+			case "branch":
+				b.Branch = v
+			case "head":
+				b.Head = v
+			case "summary":
+				b.Summary = v
+			}
+		}
+		if b.Head != "" {
+			res = append(res, b)
+		}
+	}
+	return res, nil
+}
+
+// RestoreBackup puts b's branch back where it was. The current state is
+// backed up first, so a restore can be undone too. A checked-out branch is
+// moved with "git reset --keep", which keeps local changes and refuses to
+// overwrite them; any other branch just has its ref updated. A backup of a
+// detached HEAD taken while a branch is checked out detaches HEAD at the
+// backed up commit rather than moving that branch.
+func RestoreBackup(ctx context.Context, b Backup) (Backup, error) {
+	if s, err := InProgressRebase(ctx); err == nil && s != nil {
+		return Backup{}, ErrRebaseInProgress
+	}
+	current := ""
+	if out, err := gitContext(ctx, "symbolic-ref", "-q", "HEAD"); err == nil {
+		current = strings.TrimSpace(string(out))
+	}
+	from := "HEAD"
+	if b.Branch != "" && b.Branch != current {
+		from = b.Branch
+	}
+	detach := b.Branch == "" && current != ""
+	undoBranch := b.Branch
+	if detach {
+		undoBranch = current
+	}
+	undo, err := CreateBackup(ctx, from, undoBranch, nil)
+	if err != nil {
+		return Backup{}, err
+	}
+	switch {
+	case detach:
+		// Like reset --keep, checkout refuses to overwrite local changes.
+		_, err = gitContext(ctx, "checkout", "-q", "--detach", b.Head)
+	case from == "HEAD":
+		_, err = gitContext(ctx, "reset", "-q", "--keep", b.Head)
+	default:
+		_, err = gitContext(ctx, "update-ref", "-m", "rebasei: restore "+b.Ref, b.Branch, b.Head)
+	}
+	return undo, err
+}
+
+// planSummary counts the plan's instructions by command, e.g.
+// "3 pick, 1 fixup, 1 drop".
+func planSummary(plan []CommitAction) string {
+	if len(plan) == 0 {
+		return "before restoring a backup"
+	}
+	var order []string
+	counts := map[string]int{}
+	for _, ca := range plan {
+		c := string(ca.Instruction.Command)
+		if ca.Instruction.Option != "" {
+			c += " " + ca.Instruction.Option
+		}
+		if counts[c] == 0 {
+			order = append(order, c)
+		}
+		counts[c]++
+	}
+	parts := make([]string, len(order))
+	for i, c := range order {
+		parts[i] = fmt.Sprintf("%d %s", counts[c], c)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// revParse resolves rev to a full object name.
+func revParse(ctx context.Context, rev string) (string, error) {
+	out, err := gitContext(ctx, "rev-parse", "--verify", "-q", rev)
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %q", rev)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrNoUpstream is returned by ResolveBase when no revision was given and the
+// current branch has no upstream configured.
+var ErrNoUpstream = errors.New("current branch has no upstream configured")
+
+// ErrShallow is returned when the base commit lies beyond the history
+// available in a shallow clone.
+var ErrShallow = errors.New("base commit is not available in this shallow clone; fetch more history with git fetch --deepen=<n> or --unshallow")
+
+// Base is the commit a rebase is performed onto.
+type Base struct {
+	Ref  string // what the user asked for, e.g. "origin/main" or "HEAD~5"
+	Hash string // full hash of the merge-base of Ref and HEAD; empty rebases from the root
+}
+
+// ResolveBase resolves rev to the commit the rebase should start from. rev may
+// name an upstream branch or any commit; when empty, the current branch's
+// configured upstream is used. The merge-base with HEAD is returned so that a
+// branch selects everything since the fork point and an ancestor commit
+// selects itself.
+func ResolveBase(ctx context.Context, rev string) (Base, error) {
+	if rev == "" {
+		out, err := gitContext(ctx, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+		if err != nil {
+			return Base{}, ErrNoUpstream
+		}
+		rev = strings.TrimSpace(string(out))
+	}
+	if _, err := gitContext(ctx, "rev-parse", "--verify", "--quiet", rev+"^{commit}"); err != nil {
+		return Base{}, fmt.Errorf("%s is not a valid commit", rev)
+	}
+	out, err := gitContext(ctx, "merge-base", "HEAD", rev)
+	if err != nil {
+		if ctx.Err() != nil {
+			return Base{}, ctx.Err()
+		}
+		if isShallow(ctx) {
+			return Base{}, ErrShallow
+		}
+		return Base{}, fmt.Errorf("%s has no common ancestor with HEAD", rev)
+	}
+	return Base{Ref: rev, Hash: strings.TrimSpace(string(out))}, nil
+}
+
+// WindowBase returns the base that selects the last n commits along HEAD's
+// first-parent chain, i.e. HEAD~n. Merged side branches within that window
+// belong to the range as well. When the chain is shorter than n the rebase
+// starts from the root commit, unless the chain was cut off by a shallow
+// clone, in which case ErrShallow is returned.
+func WindowBase(ctx context.Context, n int) (Base, error) {
+	// Only walk n+1 first-parent commits instead of counting all of history.
+	out, err := gitContext(ctx, "rev-list", "--first-parent", "-n", strconv.Itoa(n+1), "HEAD")
+	if err != nil {
+		return Base{}, err
+	}
+	chain := strings.Fields(string(out))
+	if len(chain) == 0 {
+		return Base{}, errors.New("no commits found; are you in a git repo?")
+	}
+	if len(chain) > n {
+		return Base{Ref: fmt.Sprintf("HEAD~%d", n), Hash: chain[n]}, nil
+	}
+	if isShallowBoundary(ctx, chain[len(chain)-1]) {
+		return Base{}, ErrShallow
+	}
+	return Base{}, nil
+}
+
+// isShallow reports whether the repository is a shallow clone.
+func isShallow(ctx context.Context) bool {
+	out, err := gitContext(ctx, "rev-parse", "--is-shallow-repository")
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// isShallowBoundary reports whether hash is a commit whose parents were cut
+// off by a shallow clone.
+func isShallowBoundary(ctx context.Context, hash string) bool {
+	if !isShallow(ctx) {
+		return false
+	}
+	path, err := gitPath(ctx, "shallow")
+	if err != nil {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	for _, h := range strings.Fields(string(data)) {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
@@ -3,10 +3,10 @@ package commands
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"os"
 	"os/exec"
-	"strconv"
 	"strings"
 )
 
@@ -17,53 +17,179 @@ type Commit struct {
 	Author    string
 	Date      string // YYYY-MM-DD
 	Tags      []string
+	Branches  []string // local branches pointing at the commit
+	// Signature is git's verdict on the commit's GPG or SSH signature (%G?):
+	// "G" good, "U" good but of unknown validity, "B" bad, "X"/"Y" expired
+	// signature or key, "R" revoked key, "E" can't be checked (e.g. the key
+	// is missing) and "N" unsigned.
+	Signature string
+	// Signer is the name on the signature (%GS).
+	Signer string
 }
 
-func ListCommits(n int) ([]Commit, error) {
-	// %D includes ref names like "HEAD -> main, tag: v1.0.0, origin/main"
-	args := []string{"log", "--date=short", "--pretty=format:%h\t%H\t%s\t%an\t%ad\t%D", "-n", strconv.Itoa(n)}
-	cmd := exec.Command("git", args...)
-	cmd.Env = append(os.Environ(), "GIT_PAGER=cat")
-	out, err := cmd.Output()
+// Signed reports whether the commit carries a signature, valid or not.
+func (c Commit) Signed() bool {
+	return c.Signature != "" && c.Signature != "N"
+}
+
+// With --decorate=full, %D lists full ref names like
+// "HEAD -> refs/heads/main, tag: refs/tags/v1.0.0, refs/remotes/origin/main"
+const logFields = "%h\t%H\t%s\t%an\t%ad\t%G?\t%GS\t%D"
+
+const logFormat = "--pretty=format:" + logFields
+
+// ListRange returns the commits a rebase onto base would pick: the non-merge
+// commits in base..HEAD, newest first, in the same topological order git
+// uses for the todo. An empty base lists everything reachable from HEAD.
+func ListRange(ctx context.Context, base string) ([]Commit, error) {
+	s, err := StreamRange(ctx, base, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.Next(-1)
 	if err != nil {
 		return nil, err
 	}
+	if len(res) == 0 {
+		return nil, errors.New("no commits between base and HEAD; nothing to rebase")
+	}
+	return res, nil
+}
+
+// CommitStream reads the commits of a range from a running git log, so a
+// long range can be shown a page at a time without waiting for all of it.
+type CommitStream struct {
+	cmd    *exec.Cmd
+	stderr bytes.Buffer
+	sc     *bufio.Scanner
+	done   bool
+}
+
+// StreamRange starts listing the non-merge commits in base..tip like
+// ListRange, or everything reachable from tip when base is empty. git is
+// killed when ctx is cancelled.
+func StreamRange(ctx context.Context, base, tip string) (*CommitStream, error) {
+	rng := tip
+	if base != "" {
+		rng = base + ".." + tip
+	}
+	args := []string{"log", "--date=short", "--decorate=full", "--no-merges", "--topo-order", logFormat, rng}
+	s := &CommitStream{cmd: runner.Command(ctx, args...)}
+	s.cmd.Stderr = &s.stderr
+	out, err := s.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cmd.Start(); err != nil {
+		return nil, newGitError(args, err)
+	}
+	s.sc = bufio.NewScanner(out)
+	return s, nil
+}
+
+// Next returns up to n more commits, or all that are left when n < 0.
+// Once the range is exhausted Done reports true and git has exited.
+func (s *CommitStream) Next(n int) ([]Commit, error) {
+	res := []Commit{}
+	for !s.done && (n < 0 || len(res) < n) {
+		if !s.sc.Scan() {
+			s.done = true
+			err := s.sc.Err()
+			if werr := s.cmd.Wait(); err == nil && werr != nil {
+				msg := strings.TrimSpace(s.stderr.String())
+				err = &GitError{Command: "log", Stderr: msg, Kind: Classify(msg), Err: werr}
+			}
+			if err != nil {
+				return res, err
+			}
+			break
+		}
+		if c, ok := parseLogLine(s.sc.Text()); ok {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+// Done reports whether all commits of the range have been read.
+func (s *CommitStream) Done() bool {
+	return s.done
+}
+
+func parseLog(out []byte) ([]Commit, error) {
 	res := []Commit{}
 	s := bufio.NewScanner(bytes.NewReader(out))
 	for s.Scan() {
-		line := s.Text()
-		parts := strings.SplitN(line, "\t", 6)
-		if len(parts) < 5 { // tolerate missing %D on some lines
-			continue
+		if c, ok := parseLogLine(s.Text()); ok {
+			res = append(res, c)
 		}
-		var tags []string
-		if len(parts) >= 6 {
-			// Parse %D for tags: look for segments starting with "tag: "
-			for _, seg := range strings.Split(parts[5], ",") {
-				seg = strings.TrimSpace(seg)
-				const p = "tag: "
-				if strings.HasPrefix(seg, p) {
-					t := strings.TrimPrefix(seg, p)
-					if t != "" {
-						tags = append(tags, t)
-					}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// parseLogLine parses a line of logFormat output.
+func parseLogLine(line string) (Commit, bool) {
+	parts := strings.SplitN(line, "\t", 8)
+	if len(parts) < 7 { // tolerate missing %D on some lines
+		return Commit{}, false
+	}
+	var tags, branches []string
+	if len(parts) >= 8 {
+		// Parse %D for tags ("tag: refs/tags/...") and local branches
+		for _, seg := range strings.Split(parts[7], ",") {
+			seg = strings.TrimSpace(seg)
+			seg = strings.TrimPrefix(seg, "HEAD -> ")
+			if t, ok := strings.CutPrefix(seg, "tag: "); ok {
+				t = strings.TrimPrefix(t, "refs/tags/")
+				if t != "" {
+					tags = append(tags, t)
 				}
+				continue
+			}
+			if b, ok := strings.CutPrefix(seg, "refs/heads/"); ok && b != "" {
+				branches = append(branches, b)
 			}
 		}
-		res = append(res, Commit{
-			HashShort: parts[0],
-			Hash:      parts[1],
-			Subject:   parts[2],
-			Author:    parts[3],
-			Date:      parts[4],
-			Tags:      tags,
-		})
 	}
-	if err := s.Err(); err != nil {
-		return nil, err
+	return Commit{
+		HashShort: parts[0],
+		Hash:      parts[1],
+		Subject:   parts[2],
+		Author:    parts[3],
+		Date:      parts[4],
+		Tags:      tags,
+		Branches:  branches,
+		Signature: parts[5],
+		Signer:    parts[6],
+	}, true
+}
+
+// CommitMessage returns the full message (subject and body) of a commit.
+func CommitMessage(ctx context.Context, hash string) (string, error) {
+	out, err := gitContext(ctx, "log", "-1", "--format=%B", hash)
+	if err != nil {
+		return "", err
 	}
-	if len(res) == 0 {
-		return nil, errors.New("no commits found; are you in a git repo?")
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// CurrentBranch returns the short name of the branch being rebased: the
+// checked-out branch, or during a rebase the branch it started from.
+// It returns "" for a detached HEAD.
+func CurrentBranch(ctx context.Context) string {
+	if out, err := gitContext(ctx, "symbolic-ref", "-q", "--short", "HEAD"); err == nil {
+		return strings.TrimSpace(string(out))
 	}
-	return res, nil
+	path, err := gitPath(ctx, "rebase-merge/head-name")
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(data)), "refs/heads/")
 }
@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Conflict is an unmerged path in the index.
+type Conflict struct {
+	// Path is relative to the top of the working tree, like every path
+	// git status reports.
+	Path string
+	// Code is the two-letter XY status from "git status --porcelain=v2",
+	// e.g. "UU" for both modified or "UD" for deleted by them.
+	Code string
+}
+
+// Kind describes the conflict the way git status does.
+func (c Conflict) Kind() string {
+	switch c.Code {
+	case "DD":
+		return "both deleted"
+	case "AU":
+		return "added by us"
+	case "UD":
+		return "deleted by them"
+	case "UA":
+		return "added by them"
+	case "DU":
+		return "deleted by us"
+	case "AA":
+		return "both added"
+	case "UU":
+		return "both modified"
+	}
+	return c.Code
+}
+
+// oursDeleted and theirsDeleted report whether a side removed the path.
+func (c Conflict) oursDeleted() bool   { return c.Code[0] == 'D' }
+func (c Conflict) theirsDeleted() bool { return c.Code[1] == 'D' }
+
+// Conflicts lists the unmerged paths of the working tree.
+func Conflicts(ctx context.Context) ([]Conflict, error) {
+	out, err := gitContext(ctx, "status", "--porcelain=v2", "-z", "--untracked-files=no")
+	if err != nil {
+		return nil, err
+	}
+	var res []Conflict
+	for _, rec := range strings.Split(string(out), "\x00") {
+		// u <XY> <sub> <m1> <m2> <m3> <mW> <h1> <h2> <h3> <path>
+		if !strings.HasPrefix(rec, "u ") {
+			continue
+		}
+		f := strings.SplitN(rec, " ", 11)
+		if len(f) == 11 {
+			res = append(res, Conflict{Path: f[10], Code: f[1]})
+		}
+	}
+	return res, nil
+}
+
+// topPath returns the pathspec of a path relative to the top of the working
+// tree, so it matches wherever in the tree git runs.
+func topPath(path string) string {
+	return ":(top)" + path
+}
+
+// ConflictDiff returns the combined diff of an unmerged path, which shows
+// its conflict hunks.
+func ConflictDiff(ctx context.Context, path string) (string, error) {
+	out, err := gitContext(ctx, "diff", "--no-color", "--no-ext-diff", "--", topPath(path))
+	return string(out), err
+}
+
+// TakeSide resolves c with one side's version and stages the result; a side
+// that deleted the path resolves it by removing it. During a rebase "ours"
+// is the branch being built and "theirs" the commit being applied.
+func TakeSide(ctx context.Context, c Conflict, ours bool) error {
+	deleted, flag := c.theirsDeleted(), "--theirs"
+	if ours {
+		deleted, flag = c.oursDeleted(), "--ours"
+	}
+	if deleted {
+		_, err := gitContext(ctx, "rm", "-q", "--", topPath(c.Path))
+		return err
+	}
+	if _, err := gitContext(ctx, "checkout", flag, "--", topPath(c.Path)); err != nil {
+		return err
+	}
+	_, err := gitContext(ctx, "add", "--", topPath(c.Path))
+	return err
+}
+
+// MarkResolved stages the path as resolved. It refuses while the file still
+// has conflict markers so they don't end up committed.
+func MarkResolved(ctx context.Context, c Conflict) error {
+	top, err := topLevel(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(top, c.Path))
+	if os.IsNotExist(err) {
+		_, err = gitContext(ctx, "rm", "-q", "--", topPath(c.Path))
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(data, []byte("\n<<<<<<< ")) || bytes.HasPrefix(data, []byte("<<<<<<< ")) {
+		return fmt.Errorf("%s still has conflict markers", c.Path)
+	}
+	_, err = gitContext(ctx, "add", "--", topPath(c.Path))
+	return err
+}
+
+// MergetoolCmd returns the command that runs the configured merge tool on
+// path. It needs the terminal. mergetool takes no pathspec magic, so it
+// runs in dir, the top of the working tree (RepoDir).
+func MergetoolCmd(dir, path string) *exec.Cmd {
+	if dir == "" {
+		return gitCommand("mergetool", "--", path)
+	}
+	return gitCommand("-C", dir, "mergetool", "--", path)
+}
@@ -0,0 +1,65 @@
+package commands_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+)
+
+func TestResolveConflictsFromSubdirectory(t *testing.T) {
+	r, _ := newRepo(t)
+	r.Commit("add sub", "sub/f.txt", "0\n", "sub/g.txt", "0\n", "sub/h.txt", "0\n")
+	base := r.Head()
+	r.Commit("one", "sub/f.txt", "1\n", "sub/g.txt", "1\n", "sub/h.txt", "1\n")
+	r.Commit("two", "sub/f.txt", "2\n", "sub/g.txt", "2\n", "sub/h.txt", "2\n")
+	plan := planOf(t, base)
+	plan[0], plan[1] = plan[1], plan[0]
+	interactiveRebase(t, commands.RebaseOptions{Base: base}, plan, true)
+
+	// Run the commands from sub, where paths relative to the top of the
+	// working tree don't resolve as they are.
+	r.Git("config", "merge.tool", "theirs")
+	r.Git("config", "mergetool.theirs.cmd", `cp "$REMOTE" "$MERGED"`)
+	r.Git("config", "mergetool.keepBackup", "false")
+	sub := r.Runner
+	sub.Dir = filepath.Join(r.Dir, "sub")
+	commands.SetRunner(sub)
+	ctx := context.Background()
+
+	cs, err := commands.Conflicts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []commands.Conflict{{Path: "sub/f.txt", Code: "UU"}, {Path: "sub/g.txt", Code: "UU"}, {Path: "sub/h.txt", Code: "UU"}}
+	if !reflect.DeepEqual(cs, want) {
+		t.Fatalf("Conflicts = %+v, want %+v", cs, want)
+	}
+	if diff, err := commands.ConflictDiff(ctx, cs[0].Path); err != nil || !strings.Contains(diff, "<<<<<<<") {
+		t.Errorf("ConflictDiff = %q, %v; want the conflict hunks", diff, err)
+	}
+
+	if err := commands.TakeSide(ctx, cs[0], true); err != nil {
+		t.Errorf("TakeSide: %v", err)
+	}
+	if err := commands.MarkResolved(ctx, cs[1]); err == nil {
+		t.Error("MarkResolved succeeded with conflict markers left")
+	}
+	r.Write("sub/g.txt", "resolved\n")
+	if err := commands.MarkResolved(ctx, cs[1]); err != nil {
+		t.Errorf("MarkResolved: %v", err)
+	}
+	run(t, commands.MergetoolCmd(r.Dir, cs[2].Path), false)
+
+	if cs, err := commands.Conflicts(ctx); err != nil || len(cs) != 0 {
+		t.Errorf("Conflicts after resolving = %+v, %v; want none", cs, err)
+	}
+	for path, want := range map[string]string{"sub/f.txt": "0\n", "sub/g.txt": "resolved\n", "sub/h.txt": "2\n"} {
+		if got := r.Git("show", ":"+path); got+"\n" != want {
+			t.Errorf("staged %s = %q, want %q", path, got, want)
+		}
+	}
+}
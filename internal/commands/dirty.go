@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+// WorkTree lists the local changes, in "git status --short" form.
+type WorkTree struct {
+	Staged    []string
+	Unstaged  []string
+	Untracked []string
+}
+
+// Dirty reports whether there are changes to tracked files, which git
+// rebase refuses to start with. Untracked files don't stop it.
+func (w WorkTree) Dirty() bool {
+	return len(w.Staged) > 0 || len(w.Unstaged) > 0
+}
+
+// WorkTreeStatus sorts the working tree status into staged, unstaged and
+// untracked changes. A path both staged and changed again is in both.
+func WorkTreeStatus(ctx context.Context) (WorkTree, error) {
+	lines, err := ShortStatus(ctx)
+	if err != nil {
+		return WorkTree{}, err
+	}
+	var w WorkTree
+	for _, l := range lines {
+		if len(l) < 3 {
+			continue
+		}
+		if l[:2] == "??" {
+			w.Untracked = append(w.Untracked, l)
+			continue
+		}
+		if l[0] != ' ' {
+			w.Staged = append(w.Staged, l)
+		}
+		if l[1] != ' ' {
+			w.Unstaged = append(w.Unstaged, l)
+		}
+	}
+	return w, nil
+}
+
+// CommitWIP commits the changes to tracked files as a "WIP" commit on
+// HEAD, so they ride along with the rebase, and returns the plan row that
+// picks it. sign signs it like the rebase's own commits. Untracked files
+// are left alone.
+func CommitWIP(ctx context.Context, sign bool) (CommitAction, error) {
+	if _, err := gitContext(ctx, "commit", "-q", "-a", signOption(sign), "-m", "WIP"); err != nil {
+		return CommitAction{}, err
+	}
+	c, err := HeadCommit(ctx)
+	if err != nil {
+		return CommitAction{}, err
+	}
+	return CommitAction{Commit: c, Instruction: todo.Instruction{Command: todo.Pick, Commit: c.Hash, Text: c.Subject}}, nil
+}
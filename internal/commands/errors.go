@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// Failures the UI can explain. A *GitError matches one of them with
+// errors.Is when git's output says what went wrong.
+var (
+	ErrGitNotFound      = errors.New("git is not installed or not on PATH")
+	ErrNotRepo          = errors.New("not a git repository")
+	ErrNoWorkTree       = errors.New("the repository has no working tree")
+	ErrUnbornBranch     = errors.New("the current branch has no commits yet")
+	ErrDetachedHead     = errors.New("HEAD is detached")
+	ErrDirtyTree        = errors.New("the working tree has uncommitted changes")
+	ErrRebaseInProgress = errors.New("a rebase is already in progress")
+	ErrLocked           = errors.New("another git process holds a lock")
+)
+
+// GitError is a git command that failed.
+type GitError struct {
+	// Command is the git subcommand, e.g. "rebase".
+	Command string
+	// Stderr is what git printed on stderr, trimmed.
+	Stderr string
+	// Kind is the recognized failure, one of the Err values above, or nil.
+	Kind error
+	// Err is the error from running git.
+	Err error
+}
+
+func (e *GitError) Error() string {
+	if e.Stderr != "" {
+		return "git " + e.Command + ": " + e.Stderr
+	}
+	if e.Kind != nil {
+		return "git " + e.Command + ": " + e.Kind.Error()
+	}
+	return "git " + e.Command + ": " + e.Err.Error()
+}
+
+// Is reports whether target is the kind of failure e was recognized as.
+func (e *GitError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+func (e *GitError) Unwrap() error {
+	return e.Err
+}
+
+// failurePatterns map phrases of git's messages, lowercased, to the
+// failure they report.
+var failurePatterns = []struct {
+	phrase string
+	kind   error
+}{
+	{"not a git repository", ErrNotRepo},
+	{"must be run in a work tree", ErrNoWorkTree},
+	{"does not have any commits yet", ErrUnbornBranch},
+	{"ambiguous argument 'head'", ErrUnbornBranch},
+	{"head does not point to a branch", ErrDetachedHead},
+	{"you are not currently on a branch", ErrDetachedHead},
+	{"you have unstaged changes", ErrDirtyTree},
+	{"your index contains uncommitted changes", ErrDirtyTree},
+	{"would be overwritten by", ErrDirtyTree},
+	{"not uptodate. cannot merge", ErrDirtyTree},
+	{"please commit or stash them", ErrDirtyTree},
+	{"already a rebase-merge directory", ErrRebaseInProgress},
+	{"already a rebase-apply directory", ErrRebaseInProgress},
+	{".lock': file exists", ErrLocked},
+	{"cannot lock ref", ErrLocked},
+}
+
+// Classify returns the failure git's output reports, or nil when it isn't
+// one the UI knows how to explain.
+func Classify(output string) error {
+	out := strings.ToLower(output)
+	for _, p := range failurePatterns {
+		if strings.Contains(out, p.phrase) {
+			return p.kind
+		}
+	}
+	return nil
+}
+
+// newGitError wraps the error of running git with args.
+func newGitError(args []string, err error) error {
+	e := &GitError{Command: subcommand(args), Err: err}
+	var ee *exec.ExitError
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		e.Kind = ErrGitNotFound
+	case errors.As(err, &ee):
+		e.Stderr = strings.TrimSpace(string(ee.Stderr))
+		e.Kind = Classify(e.Stderr)
+	}
+	return e
+}
+
+// CheckRepo makes sure git can be run here: git is installed, the current
+// directory is in a repository with a working tree, and HEAD has commits to
+// rebase.
+func CheckRepo(ctx context.Context) error {
+	// Not --is-inside-work-tree: with GIT_WORK_TREE set, git works from
+	// outside the working tree too.
+	out, err := gitContext(ctx, "rev-parse", "--is-bare-repository")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(out)) == "true" {
+		return &GitError{Command: "rev-parse", Kind: ErrNoWorkTree, Err: ErrNoWorkTree}
+	}
+	if _, err := gitContext(ctx, "rev-parse", "--verify", "-q", "HEAD"); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &GitError{Command: "rev-parse", Kind: ErrUnbornBranch, Err: err}
+	}
+	return nil
+}
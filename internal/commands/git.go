@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Runner runs git for the commands of this package.
+type Runner interface {
+	// Run runs a non-interactive git command and returns its stdout. env is
+	// added to the environment and stdin, when not empty, is fed to git.
+	// On failure the error is a *GitError holding git's stderr.
+	Run(ctx context.Context, env []string, stdin string, args ...string) ([]byte, error)
+	// Command prepares a git command that runs attached to the terminal,
+	// for commands that may stop or open an editor.
+	Command(ctx context.Context, args ...string) *exec.Cmd
+}
+
+// Exec is the Runner that runs the git binary.
+type Exec struct {
+	// Dir is the directory git runs in; empty means the current directory.
+	Dir string
+	// Env is added to the process's environment.
+	Env []string
+}
+
+// Run implements Runner.
+func (e Exec) Run(ctx context.Context, env []string, stdin string, args ...string) ([]byte, error) {
+	cmd := e.Command(ctx, args...)
+	cmd.Env = append(cmd.Env, env...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return out, newGitError(args, err)
+	}
+	return out, nil
+}
+
+// Command implements Runner.
+func (e Exec) Command(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = e.Dir
+	cmd.Env = append(append(os.Environ(), "GIT_PAGER=cat"), e.Env...)
+	return cmd
+}
+
+// runner is what every command in this package runs git with.
+var runner Runner = Exec{}
+
+// SetRunner makes the commands of this package run git with r, e.g. in
+// another repository. Call it before running any command.
+func SetRunner(r Runner) {
+	runner = r
+}
+
+// OpenRepo makes the commands of this package work in dir, like "git -C
+// dir": git finds the repository from there, which may be a linked
+// worktree or a submodule checkout, unless GIT_DIR and GIT_WORK_TREE say
+// otherwise. Relative values of those are then read from dir too.
+func OpenRepo(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if fi, err := os.Stat(abs); err != nil {
+		var pe *fs.PathError
+		if errors.As(err, &pe) {
+			err = pe.Err
+		}
+		return fmt.Errorf("cannot change to %q: %w", dir, err)
+	} else if !fi.IsDir() {
+		return fmt.Errorf("cannot change to %q: not a directory", dir)
+	}
+	SetRunner(Exec{Dir: abs})
+	return nil
+}
+
+// git runs a non-interactive git command and returns its stdout.
+// On failure the error includes git's stderr so callers can surface it.
+func git(args ...string) ([]byte, error) {
+	return gitContext(context.Background(), args...)
+}
+
+// gitContext is git that is killed when ctx is cancelled.
+func gitContext(ctx context.Context, args ...string) ([]byte, error) {
+	return runner.Run(ctx, nil, "", args...)
+}
+
+// gitWith is git with extra environment variables and input on stdin.
+func gitWith(env []string, stdin string, args ...string) ([]byte, error) {
+	return runner.Run(context.Background(), env, stdin, args...)
+}
+
+// gitCommand prepares a git command that needs the terminal.
+func gitCommand(args ...string) *exec.Cmd {
+	return runner.Command(context.Background(), args...)
+}
+
+// gitPath returns the absolute path of a file in the git directory, such
+// as "rebase-merge" or "shallow".
+func gitPath(ctx context.Context, name string) (string, error) {
+	out, err := gitContext(ctx, "rev-parse", "--path-format=absolute", "--git-path", name)
+	return strings.TrimSpace(string(out)), err
+}
+
+// topLevel returns the absolute path of the working tree's top directory,
+// which paths reported by git status are relative to.
+func topLevel(ctx context.Context) (string, error) {
+	out, err := gitContext(ctx, "rev-parse", "--show-toplevel")
+	return strings.TrimSpace(string(out)), err
+}
+
+// RepoDir returns the top directory of the working tree being rebased, or
+// "" when there is none.
+func RepoDir(ctx context.Context) string {
+	dir, _ := topLevel(ctx)
+	return dir
+}
+
+// runnerEnv returns the environment git runs with, for other programs
+// started in the repository.
+func runnerEnv() []string {
+	if e, ok := runner.(Exec); ok {
+		return append(os.Environ(), e.Env...)
+	}
+	return nil
+}
+
+// ConfigBool reports whether the boolean git config key is set to true.
+func ConfigBool(ctx context.Context, key string) bool {
+	out, err := gitContext(ctx, "config", "--type=bool", "--get", key)
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// subcommand returns the git command in args, skipping "-c key=value"
+// options in front of it.
+func subcommand(args []string) string {
+	i := 0
+	for i+2 < len(args) && args[i] == "-c" {
+		i += 2
+	}
+	return args[i]
+}
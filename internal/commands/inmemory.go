@@ -0,0 +1,309 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+// ErrNeedsStop is returned by RebaseInMemory when the plan can't run
+// without git stopping, e.g. on a conflict. Nothing has been changed then,
+// and the plan should run as a normal rebase instead.
+var ErrNeedsStop = errors.New("the plan needs a real rebase")
+
+// commitInfo is what rewriting a commit needs to know about it.
+type commitInfo struct {
+	parent, tree, parentTree string
+	authorName, authorEmail  string
+	// authorDate is in git's raw format, "<unix seconds> <zone>".
+	authorDate string
+	message    string
+}
+
+// InMemory reports whether the plan (newest first) could run without a
+// stop or an editor: only picks, drops, fixups without -c, rewords with a
+// new message, squash groups with a composed message and update-ref rows,
+// no merges and no autostash. Signed rebases run in git, where gpg or
+// ssh-agent can ask for a passphrase. So do rebases the repository has a
+// post-rewrite hook for, and plans with new messages when it has a
+// commit-msg hook: commit-tree runs neither.
+func InMemory(ctx context.Context, opts RebaseOptions, list []CommitAction) bool {
+	if opts.RebaseMerges || opts.Autostash || opts.Sign || len(list) == 0 {
+		return false
+	}
+	composed := composedRows(list)
+	messages := false
+	for i, ca := range list {
+		switch ca.Instruction.Command {
+		case todo.Pick, todo.Drop, todo.UpdateRef:
+		case todo.Reword:
+			if ca.Message == "" {
+				return false
+			}
+			messages = true
+		case todo.Squash:
+			if !composed[i] {
+				return false
+			}
+			messages = true
+		case todo.Fixup:
+			if ca.Instruction.Option == "-c" {
+				if !composed[i] {
+					return false
+				}
+				messages = true
+			}
+		default:
+			return false
+		}
+	}
+	if hasHook(ctx, "post-rewrite") {
+		return false
+	}
+	return !messages || !hasHook(ctx, "commit-msg")
+}
+
+// hasHook reports whether the repository has the hook name, e.g.
+// "commit-msg", which git runs when it is an executable file.
+func hasHook(ctx context.Context, name string) bool {
+	path, err := gitPath(ctx, "hooks/"+name)
+	if err != nil {
+		return false
+	}
+	fi, err := os.Stat(path)
+	return err == nil && fi.Mode().IsRegular() && fi.Mode()&0o111 != 0
+}
+
+// RebaseInMemory runs the plan (newest first) without a checkout: the new
+// commits are written with merge-tree and commit-tree, then HEAD and the
+// branches of the plan's update-ref rows are moved in one transaction, and
+// the index and working tree are updated like "git checkout" would. Local
+// changes are kept unless they touch the files the rebase changes. It
+// returns the number of commits rewritten; ErrNeedsStop means nothing was
+// done because the plan would stop.
+func RebaseInMemory(opts RebaseOptions, list []CommitAction) (int, error) {
+	// Once started, the rewrite isn't cancelled halfway.
+	ctx := context.Background()
+	if !InMemory(ctx, opts, list) {
+		return 0, fmt.Errorf("%w: it stops, opens an editor or runs hooks", ErrNeedsStop)
+	}
+	head, err := revParse(ctx, "HEAD")
+	if err != nil {
+		return 0, err
+	}
+	var hashes []string
+	for _, ca := range list {
+		if ca.Instruction.Command.TakesCommit() && ca.Instruction.Command != todo.Drop {
+			hashes = append(hashes, ca.Commit.Hash)
+		}
+	}
+	infos, err := loadCommitInfos(hashes)
+	if err != nil {
+		return 0, err
+	}
+
+	tip, tipTree := opts.Base, emptyTree
+	if opts.Base != "" {
+		if tipTree, err = revParse(ctx, opts.Base+"^{tree}"); err != nil {
+			return 0, err
+		}
+	}
+	// last is the commit at the tip, whose author and message a fixup keeps.
+	var last commitInfo
+	var refUpdates strings.Builder
+	rewritten := 0
+	p := NewPredictor()
+	for i := len(list) - 1; i >= 0; i-- {
+		ca := list[i]
+		ins := ca.Instruction
+		switch ins.Command {
+		case todo.Drop:
+			continue
+		case todo.UpdateRef:
+			old, err := revParse(ctx, ins.Arg)
+			if err != nil {
+				return 0, err
+			}
+			fmt.Fprintf(&refUpdates, "update %s %s %s\n", ins.Arg, tip, old)
+			continue
+		}
+		c, ok := infos[ca.Commit.Hash]
+		if !ok {
+			return 0, fmt.Errorf("unknown commit %s", ca.Commit.HashShort)
+		}
+		if ins.Command == todo.Pick && tip == c.parent {
+			// Still on the original history: keep the commit as it is.
+			tip, tipTree, last = ca.Commit.Hash, c.tree, c
+			continue
+		}
+		melds := ins.Command == todo.Fixup || ins.Command == todo.Squash
+		if melds && tip == opts.Base {
+			return 0, fmt.Errorf("cannot %s %s without a previous commit", ins.Command, ca.Commit.HashShort)
+		}
+		step, err := p.apply(ctx, c.parentTree, tipTree, c.tree)
+		if err != nil {
+			return 0, err
+		}
+		if len(step.conflicts) > 0 {
+			return 0, fmt.Errorf("%w: %s conflicts in %s", ErrNeedsStop, ca.Commit.HashShort, strings.Join(step.conflicts, ", "))
+		}
+		if melds {
+			msg := last.message
+			switch {
+			case ca.Message != "":
+				// The message composed for the whole group.
+				msg = cleanupMessage(ca.Message)
+			case ins.Option != "":
+				msg = amendMessage(c.message)
+			}
+			if tip, err = writeCommit(step.tree, last.parent, last, msg); err != nil {
+				return 0, err
+			}
+			tipTree, last.tree, last.message = step.tree, step.tree, msg
+			rewritten++
+			continue
+		}
+		if step.tree == tipTree && c.tree != c.parentTree {
+			// git stops to ask what to do with a commit that became empty.
+			return 0, fmt.Errorf("%w: %s becomes empty", ErrNeedsStop, ca.Commit.HashShort)
+		}
+		msg := c.message
+		if ins.Command == todo.Reword {
+			msg = cleanupMessage(ca.Message)
+		}
+		c.parent, c.tree, c.message = tip, step.tree, msg
+		if tip, err = writeCommit(step.tree, c.parent, c, msg); err != nil {
+			return 0, err
+		}
+		tipTree, last = step.tree, c
+		rewritten++
+	}
+	if tip == head && refUpdates.Len() == 0 {
+		return 0, nil
+	}
+
+	headTree, err := revParse(ctx, "HEAD^{tree}")
+	if err != nil {
+		return 0, err
+	}
+	if headTree != tipTree {
+		// A two-tree merge updates only what differs between the trees and
+		// refuses to overwrite local changes to those files.
+		if _, err := git("read-tree", "-m", "-u", head, tip); err != nil {
+			return 0, err
+		}
+	}
+	reason := "rebasei: in-memory rebase"
+	if opts.Base != "" {
+		reason += " onto " + opts.Base
+	}
+	// The updates of a single --stdin run are applied atomically.
+	if _, err := gitWith(nil, fmt.Sprintf("update HEAD %s %s\n", tip, head)+refUpdates.String(),
+		"update-ref", "-m", reason, "--stdin"); err != nil {
+		if headTree != tipTree {
+			git("read-tree", "-m", "-u", tip, head)
+		}
+		return 0, err
+	}
+	// Like git rebase, leave the old tip in ORIG_HEAD.
+	git("update-ref", "ORIG_HEAD", head)
+	return rewritten, nil
+}
+
+// loadCommitInfos reads the trees, authors and messages of the commits.
+func loadCommitInfos(hashes []string) (map[string]commitInfo, error) {
+	res := map[string]commitInfo{}
+	if len(hashes) == 0 {
+		return res, nil
+	}
+	out, err := git(append([]string{"log", "--no-walk=unsorted", "--date=raw",
+		"--format=%H%x00%P%x00%T%x00%an%x00%ae%x00%ad%x00%B%x1e"}, hashes...)...)
+	if err != nil {
+		return nil, err
+	}
+	var parents []string
+	for _, rec := range bytes.Split(out, []byte("\x1e")) {
+		f := strings.SplitN(strings.TrimLeft(string(rec), "\n"), "\x00", 7)
+		if len(f) != 7 {
+			continue
+		}
+		parent, _, _ := strings.Cut(f[1], " ")
+		res[f[0]] = commitInfo{parent: parent, tree: f[2], parentTree: emptyTree,
+			authorName: f[3], authorEmail: f[4], authorDate: f[5], message: f[6]}
+		if parent != "" {
+			parents = append(parents, parent)
+		}
+	}
+	if len(parents) > 0 {
+		out, err := git(append([]string{"log", "--no-walk=unsorted", "--format=%H %T"}, parents...)...)
+		if err != nil {
+			return nil, err
+		}
+		trees := map[string]string{}
+		for _, l := range strings.Split(string(out), "\n") {
+			if h, t, ok := strings.Cut(l, " "); ok {
+				trees[h] = t
+			}
+		}
+		for h, c := range res {
+			if c.parent != "" {
+				c.parentTree = trees[c.parent]
+				res[h] = c
+			}
+		}
+	}
+	return res, nil
+}
+
+// writeCommit writes a commit of tree on parent (none when empty) with
+// msg, keeping author's name, email and date. The committer is the current
+// user, as with git rebase.
+func writeCommit(tree, parent string, author commitInfo, msg string) (string, error) {
+	args := []string{"commit-tree", tree, "-F", "-"}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	env := []string{
+		"GIT_AUTHOR_NAME=" + author.authorName,
+		"GIT_AUTHOR_EMAIL=" + author.authorEmail,
+		"GIT_AUTHOR_DATE=@" + author.authorDate,
+	}
+	out, err := gitWith(env, msg, args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// amendMessage returns the message "fixup -C" uses from an "amend!" commit:
+// its body, without the "amend! <subject>" line.
+func amendMessage(msg string) string {
+	if strings.HasPrefix(msg, "amend! ") {
+		if _, body, ok := strings.Cut(msg, "\n\n"); ok {
+			return body
+		}
+	}
+	return msg
+}
+
+// cleanupMessage tidies a message like "git commit --cleanup=whitespace":
+// trailing spaces and leading, trailing and repeated blank lines go.
+func cleanupMessage(msg string) string {
+	var lines []string
+	blank := false
+	for _, l := range strings.Split(msg, "\n") {
+		l = strings.TrimRight(l, " \t\r")
+		if l == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, l)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
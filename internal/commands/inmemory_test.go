@@ -0,0 +1,228 @@
+package commands_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+// rebaseInMemory runs the plan in memory and returns how many commits were
+// rewritten.
+func rebaseInMemory(t *testing.T, base string, plan []commands.CommitAction) int {
+	t.Helper()
+	opts := commands.RebaseOptions{Base: base}
+	if !commands.InMemory(context.Background(), opts, plan) {
+		t.Fatal("InMemory = false, want the plan to run in memory")
+	}
+	n, err := commands.RebaseInMemory(opts, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestRebaseInMemoryPickAndDrop(t *testing.T) {
+	r, base := newRepo(t)
+	r.Linear("A", "B", "C")
+	plan := planOf(t, base)
+	row(t, plan, "B").Instruction.Command = todo.Drop
+
+	if n := rebaseInMemory(t, base, plan); n != 1 {
+		t.Errorf("rewritten = %d, want 1: A is kept as it is", n)
+	}
+	if got, want := r.Subjects(base, "HEAD"), []string{"C", "A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("subjects = %q, want %q", got, want)
+	}
+	// The working tree follows, without local changes left behind.
+	if got := r.Git("ls-files"); got != "base.txt\nfile1.txt\nfile3.txt" {
+		t.Errorf("files = %q", got)
+	}
+	if st := r.Status(); st != nil {
+		t.Errorf("status = %q, want clean", st)
+	}
+	if got := r.Rev("ORIG_HEAD"); got != plan[0].Commit.Hash {
+		t.Errorf("ORIG_HEAD = %s, want the old tip", got)
+	}
+}
+
+func TestRebaseInMemoryNothingToDo(t *testing.T) {
+	r, base := newRepo(t)
+	r.Linear("A", "B")
+	head := r.Head()
+	if n := rebaseInMemory(t, base, planOf(t, base)); n != 0 || r.Head() != head {
+		t.Errorf("rewritten = %d, HEAD = %s; want nothing rewritten", n, r.Head())
+	}
+}
+
+func TestRebaseInMemoryFixup(t *testing.T) {
+	r, base := newRepo(t)
+	r.Linear("A", "B", "fixup! A", "amend! B")
+	r.Git("commit", "--amend", "-q", "-m", "amend! B", "-m", "B with a new message")
+	plan, moved := commands.Autosquash(planOf(t, base))
+	if moved != 2 {
+		t.Fatalf("Autosquash moved %d, want 2", moved)
+	}
+
+	rebaseInMemory(t, base, plan)
+
+	if got, want := r.Subjects(base, "HEAD"), []string{"B with a new message", "A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("subjects = %q, want %q", got, want)
+	}
+	if got := r.Read("file1.txt"); got != "A\nfixup! A\n" {
+		t.Errorf("file1.txt = %q, want A's change with the fixup", got)
+	}
+	if got := r.Git("show", "HEAD~:file1.txt"); got != "A\nfixup! A" {
+		t.Errorf("A's file1.txt = %q, want the fixup melded in", got)
+	}
+}
+
+func TestRebaseInMemoryReword(t *testing.T) {
+	r, base := newRepo(t)
+	r.Linear("A", "B")
+	plan := planOf(t, base)
+	a := row(t, plan, "A")
+	a.Instruction.Command = todo.Reword
+	a.Message = "A, reworded\n\nWith a body.\n\n"
+
+	if n := rebaseInMemory(t, base, plan); n != 2 {
+		t.Errorf("rewritten = %d, want 2", n)
+	}
+	if got := r.Git("log", "-1", "--format=%B", "HEAD~"); got != "A, reworded\n\nWith a body." {
+		t.Errorf("message = %q", got)
+	}
+	// The author is kept; the rewrite only changes the committer.
+	if got, want := r.Git("log", "-1", "--format=%an %ad", "HEAD~"), r.Git("log", "-1", "--format=%an %ad", a.Commit.Hash); got != want {
+		t.Errorf("author = %q, want %q", got, want)
+	}
+}
+
+func TestRebaseInMemoryUpdateRef(t *testing.T) {
+	r, base := newRepo(t)
+	r.Linear("A", "B", "C")
+	r.Branch("stack", "HEAD~")
+	plan := planOf(t, base)
+	row(t, plan, "A").Instruction.Command = todo.Drop
+	// The update-ref row follows B, above it in the newest-first plan.
+	update := commands.CommitAction{Instruction: todo.Instruction{Command: todo.UpdateRef, Arg: "refs/heads/stack"}}
+	plan = append(plan[:1], append([]commands.CommitAction{update}, plan[1:]...)...)
+
+	rebaseInMemory(t, base, plan)
+
+	if got, want := r.Subjects(base, "stack"), []string{"B"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stack subjects = %q, want %q", got, want)
+	}
+	if r.Rev("stack") != r.Rev("HEAD~") {
+		t.Error("stack doesn't point at the rewritten B")
+	}
+}
+
+func TestRebaseInMemoryConflictNeedsStop(t *testing.T) {
+	r, base := newRepo(t)
+	r.Commit("one", "f.txt", "1\n")
+	r.Commit("two", "f.txt", "2\n")
+	head := r.Head()
+	plan := planOf(t, base)
+	plan[0], plan[1] = plan[1], plan[0]
+
+	_, err := commands.RebaseInMemory(commands.RebaseOptions{Base: base}, plan)
+	if !errors.Is(err, commands.ErrNeedsStop) {
+		t.Fatalf("err = %v, want ErrNeedsStop", err)
+	}
+	if r.Head() != head || r.Status() != nil {
+		t.Error("the repository changed although the rebase needs a stop")
+	}
+}
+
+func TestRebaseInMemoryEmptyNeedsStop(t *testing.T) {
+	r, base := newRepo(t)
+	r.Commit("add f", "f.txt", "f\n")
+	r.Checkout(base)
+	same := r.Commit("add f again", "f.txt", "f\n")
+	r.Checkout("main")
+	head := r.Head()
+	// Picking the same change again would leave an empty commit.
+	plan := planOf(t, base)
+	plan = append([]commands.CommitAction{{
+		Commit:      commands.Commit{Hash: same, HashShort: same[:7], Subject: "add f again"},
+		Instruction: todo.Instruction{Command: todo.Pick, Commit: same, Text: "add f again"},
+	}}, plan...)
+
+	_, err := commands.RebaseInMemory(commands.RebaseOptions{Base: base}, plan)
+	if !errors.Is(err, commands.ErrNeedsStop) {
+		t.Fatalf("err = %v, want ErrNeedsStop", err)
+	}
+	if r.Head() != head {
+		t.Error("HEAD moved although the rebase needs a stop")
+	}
+}
+
+func TestInMemory(t *testing.T) {
+	newRepo(t)
+	pick := commands.CommitAction{Instruction: todo.Instruction{Command: todo.Pick}}
+	with := func(cmd todo.Command, option, msg string) []commands.CommitAction {
+		return []commands.CommitAction{{Instruction: todo.Instruction{Command: cmd, Option: option}, Message: msg}, pick}
+	}
+	tests := []struct {
+		name string
+		opts commands.RebaseOptions
+		plan []commands.CommitAction
+		want bool
+	}{
+		{"picks", commands.RebaseOptions{}, with(todo.Pick, "", ""), true},
+		{"reword with a message", commands.RebaseOptions{}, with(todo.Reword, "", "new"), true},
+		{"reword in the editor", commands.RebaseOptions{}, with(todo.Reword, "", ""), false},
+		{"fixup -C", commands.RebaseOptions{}, with(todo.Fixup, "-C", ""), true},
+		{"fixup -c", commands.RebaseOptions{}, with(todo.Fixup, "-c", ""), false},
+		{"composed squash", commands.RebaseOptions{}, with(todo.Squash, "", "msg"), true},
+		{"squash", commands.RebaseOptions{}, with(todo.Squash, "", ""), false},
+		{"edit", commands.RebaseOptions{}, with(todo.Edit, "", ""), false},
+		{"exec", commands.RebaseOptions{}, with(todo.Exec, "", ""), false},
+		{"signed", commands.RebaseOptions{Sign: true}, with(todo.Pick, "", ""), false},
+		{"autostash", commands.RebaseOptions{Autostash: true}, with(todo.Pick, "", ""), false},
+		{"merges", commands.RebaseOptions{RebaseMerges: true}, with(todo.Pick, "", ""), false},
+		{"empty", commands.RebaseOptions{}, nil, false},
+	}
+	for _, tt := range tests {
+		if got := commands.InMemory(context.Background(), tt.opts, tt.plan); got != tt.want {
+			t.Errorf("%s: InMemory = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInMemoryHooks(t *testing.T) {
+	r, _ := newRepo(t)
+	ctx := context.Background()
+	pick := commands.CommitAction{Instruction: todo.Instruction{Command: todo.Pick}}
+	reword := commands.CommitAction{Instruction: todo.Instruction{Command: todo.Reword}, Message: "new"}
+	hook := func(name string) {
+		t.Helper()
+		r.Write("hooks/"+name, "#!/bin/sh\nexit 0\n")
+		if err := os.Chmod(filepath.Join(r.Dir, "hooks", name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Git("config", "core.hooksPath", "hooks")
+
+	// A hook that isn't executable doesn't run.
+	r.Write("hooks/commit-msg", "#!/bin/sh\nexit 1\n")
+	if !commands.InMemory(ctx, commands.RebaseOptions{}, []commands.CommitAction{reword, pick}) {
+		t.Error("reword with a commit-msg hook that doesn't run: InMemory = false, want true")
+	}
+	hook("commit-msg")
+	if commands.InMemory(ctx, commands.RebaseOptions{}, []commands.CommitAction{reword, pick}) {
+		t.Error("reword with a commit-msg hook: InMemory = true, want false")
+	}
+	if !commands.InMemory(ctx, commands.RebaseOptions{}, []commands.CommitAction{pick, pick}) {
+		t.Error("picks with a commit-msg hook: InMemory = false, want true")
+	}
+	hook("post-rewrite")
+	if commands.InMemory(ctx, commands.RebaseOptions{}, []commands.CommitAction{pick, pick}) {
+		t.Error("picks with a post-rewrite hook: InMemory = true, want false")
+	}
+}
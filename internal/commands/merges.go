@@ -0,0 +1,218 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+// topoCommit is a commit together with its parents' full hashes.
+type topoCommit struct {
+	Commit
+	Parents []string
+}
+
+// listTopology returns the commits in base..HEAD, oldest first in
+// topological order, with their parents.
+func listTopology(ctx context.Context, base string) ([]topoCommit, error) {
+	out, err := gitContext(ctx, "log", "--date=short", "--decorate=full", "--topo-order", "--reverse",
+		"--pretty=format:%P\t"+logFields, base+"..HEAD")
+	if err != nil {
+		return nil, err
+	}
+	var res []topoCommit
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		parents, rest, ok := strings.Cut(s.Text(), "\t")
+		if !ok {
+			continue
+		}
+		cs, err := parseLog([]byte(rest))
+		if err != nil {
+			return nil, err
+		}
+		if len(cs) == 1 {
+			res = append(res, topoCommit{Commit: cs[0], Parents: strings.Fields(parents)})
+		}
+	}
+	return res, s.Err()
+}
+
+// MergesPlan builds a --rebase-merges plan for base..HEAD, newest first.
+// It mirrors the todo git itself generates: every side branch is rebuilt
+// after a "reset" to its fork point and finished with a "label", and merge
+// commits are recreated with "merge -C" from those labels.
+func MergesPlan(ctx context.Context, base string) ([]CommitAction, error) {
+	if base == "" {
+		return nil, errors.New("rebasing merges needs a base commit")
+	}
+	commits, err := listTopology(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, errors.New("no commits between base and HEAD; nothing to rebase")
+	}
+	byHash := map[string]topoCommit{}
+	for _, c := range commits {
+		byHash[c.Hash] = c
+	}
+	labels := newLabeler()
+	labels.label(base, "onto")
+
+	// First phase: queue a pick or merge per commit and name the tips of
+	// merged branches after the merge that brings them in.
+	rows := map[string]CommitAction{}
+	var tips []string
+	for _, c := range commits {
+		if len(c.Parents) <= 1 {
+			rows[c.Hash] = CommitAction{
+				Commit:      c.Commit,
+				Instruction: todo.Instruction{Command: todo.Pick, Commit: c.HashShort, Text: c.Subject},
+			}
+			continue
+		}
+		name := mergeLabel(c.Subject)
+		var args []string
+		for _, p := range c.Parents[1:] {
+			if _, ok := byHash[p]; !ok {
+				args = append(args, labels.label(p, ""))
+				continue
+			}
+			tips = append(tips, p)
+			args = append(args, labels.label(p, name))
+		}
+		rows[c.Hash] = CommitAction{
+			Commit: c.Commit,
+			Instruction: todo.Instruction{
+				Command: todo.Merge, Option: "-C", Commit: c.HashShort,
+				Arg: strings.Join(args, " "), Text: "# " + c.Subject,
+			},
+		}
+	}
+
+	// Second phase: label branch points and treat HEAD as the final tip.
+	childSeen := map[string]bool{}
+	for i, c := range commits {
+		for _, p := range c.Parents {
+			if _, ok := byHash[p]; !ok {
+				continue
+			}
+			if childSeen[p] {
+				labels.label(p, "branch-point")
+			}
+			childSeen[p] = true
+		}
+		if i == len(commits)-1 {
+			tips = append(tips, c.Hash)
+		}
+	}
+
+	// Third phase: walk back from every tip along first parents, emitting
+	// each not-yet-shown stretch after a reset to where it starts.
+	ins := []CommitAction{{Instruction: todo.Instruction{Command: todo.Label, Arg: "onto"}}}
+	shown := map[string]bool{}
+	for _, tip := range tips {
+		if shown[tip] {
+			continue
+		}
+		var stretch []string
+		cur := tip
+		for {
+			c, ok := byHash[cur]
+			if !ok || shown[cur] {
+				break
+			}
+			stretch = append([]string{cur}, stretch...)
+			if len(c.Parents) == 0 {
+				cur = ""
+				break
+			}
+			cur = c.Parents[0]
+		}
+		reset := todo.Instruction{Command: todo.Reset, Arg: "onto"}
+		if cur != "" {
+			to, ok := labels.names[cur]
+			if !ok {
+				to = labels.label(cur, "")
+			}
+			if to != "onto" {
+				reset.Arg = to
+				if c, ok := byHash[cur]; ok {
+					reset.Text = "# " + c.Subject
+				}
+			}
+		}
+		ins = append(ins, CommitAction{Instruction: reset})
+		for _, h := range stretch {
+			ins = append(ins, rows[h])
+			if l, ok := labels.names[h]; ok {
+				ins = append(ins, CommitAction{Instruction: todo.Instruction{Command: todo.Label, Arg: l}})
+			}
+			shown[h] = true
+		}
+	}
+	// Reverse into newest-first order.
+	for i, j := 0, len(ins)-1; i < j; i, j = i+1, j-1 {
+		ins[i], ins[j] = ins[j], ins[i]
+	}
+	return ins, nil
+}
+
+// mergeLabel derives a label for a merged branch from the merge subject,
+// e.g. "Merge branch 'feature'" gives "feature".
+func mergeLabel(subject string) string {
+	if rest, ok := strings.CutPrefix(subject, "Merge "); ok {
+		if i := strings.Index(rest, "'"); i >= 0 {
+			if j := strings.Index(rest[i+1:], "'"); j >= 0 {
+				return rest[i+1 : i+1+j]
+			}
+		}
+	}
+	if rest, ok := strings.CutPrefix(subject, "Merge pull request "); ok {
+		if _, from, ok := strings.Cut(rest, " from "); ok {
+			return from
+		}
+	}
+	return subject
+}
+
+// labeler hands out unique, ref-safe label names per commit.
+type labeler struct {
+	names map[string]string // commit hash -> label
+	used  map[string]bool
+}
+
+func newLabeler() *labeler {
+	return &labeler{names: map[string]string{}, used: map[string]bool{}}
+}
+
+var unsafeLabelChars = regexp.MustCompile(`[^A-Za-z0-9._/-]+`)
+
+// label returns the label for hash, creating it from name when the commit
+// has none yet. An empty name labels the commit by its hash.
+func (l *labeler) label(hash, name string) string {
+	if n, ok := l.names[hash]; ok {
+		return n
+	}
+	if name == "" {
+		name = hash
+	}
+	name = strings.Trim(unsafeLabelChars.ReplaceAllString(name, "-"), "-")
+	if name == "" {
+		name = "label"
+	}
+	unique := name
+	for i := 2; l.used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	l.names[hash] = unique
+	l.used[unique] = true
+	return unique
+}
@@ -0,0 +1,99 @@
+package commands_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/gittest"
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+// mergeHistory commits A on main, then F1 and F2 on a feature branch from
+// A and B on main, merges feature and commits C on top. Each commit adds
+// its own file so the branches merge cleanly. It returns the hash of F2.
+func mergeHistory(r *gittest.Repo) string {
+	r.Commit("A", "a.txt", "A\n")
+	r.Branch("feature", "HEAD")
+	r.Checkout("feature")
+	r.Commit("F1", "f1.txt", "F1\n")
+	f2 := r.Commit("F2", "f2.txt", "F2\n")
+	r.Checkout("main")
+	r.Commit("B", "b.txt", "B\n")
+	r.Merge("feature", "Merge branch 'feature'")
+	r.Commit("C", "c.txt", "C\n")
+	return f2
+}
+
+// todoRows describes a plan oldest first like the todo, with subjects in
+// place of the commit hashes.
+func todoRows(plan []commands.CommitAction) []string {
+	res := make([]string, len(plan))
+	for i, ca := range plan {
+		ins := ca.Instruction
+		f := []string{string(ins.Command), ins.Option, ins.Arg, strings.TrimPrefix(ins.Text, "# ")}
+		res[len(plan)-1-i] = strings.Join(strings.Fields(strings.Join(f, " ")), " ")
+	}
+	return res
+}
+
+func TestMergesPlan(t *testing.T) {
+	r, base := newRepo(t)
+	mergeHistory(r)
+	plan, err := commands.MergesPlan(context.Background(), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"label onto",
+		"reset onto",
+		"pick A",
+		"label branch-point",
+		"pick F1",
+		"pick F2",
+		"label feature",
+		"reset branch-point A",
+		"pick B",
+		"merge -C feature Merge branch 'feature'",
+		"pick C",
+	}
+	if got := todoRows(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("MergesPlan =\n%q\nwant\n%q", got, want)
+	}
+	if m := row(t, plan, "Merge branch 'feature'"); m.Instruction.Commit != m.Commit.HashShort {
+		t.Errorf("merge row recreates %s, want %s", m.Instruction.Commit, m.Commit.HashShort)
+	}
+
+	if _, err := commands.MergesPlan(context.Background(), ""); err == nil {
+		t.Error("MergesPlan without a base succeeded")
+	}
+	r.Checkout(base)
+	if _, err := commands.MergesPlan(context.Background(), base); err == nil {
+		t.Error("MergesPlan with nothing to rebase succeeded")
+	}
+}
+
+func TestInteractiveRebaseMerges(t *testing.T) {
+	r, base := newRepo(t)
+	f2 := mergeHistory(r)
+	plan, err := commands.MergesPlan(context.Background(), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row(t, plan, "B").Instruction.Command = todo.Drop
+
+	interactiveRebase(t, commands.RebaseOptions{Base: base, RebaseMerges: true}, plan, false)
+
+	if got, want := strings.Split(r.Git("log", "--first-parent", "--format=%s", base+"..HEAD"), "\n"), []string{"C", "Merge branch 'feature'", "A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("first-parent subjects = %q, want %q", got, want)
+	}
+	// The merge is recreated, and the side branch is left as it was.
+	if got := r.Rev("HEAD~^2"); got != f2 {
+		t.Errorf("merged side = %s, want F2 %s", got, f2)
+	}
+	if got := r.Git("ls-files"); got != "a.txt\nbase.txt\nc.txt\nf1.txt\nf2.txt" {
+		t.Errorf("files = %q", got)
+	}
+}
@@ -0,0 +1,217 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+// emptyTree is the object name of the empty tree, the base of root commits.
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// Predictor simulates a linear plan without touching the working tree or
+// any ref, to tell which rows would stop with conflicts. Results of single
+// steps are cached, so re-predicting after a small edit only redoes the
+// steps that changed.
+type Predictor struct {
+	mu    sync.Mutex
+	steps map[[3]string]predictedStep
+	trees map[string]commitTrees
+}
+
+// predictedStep is the outcome of applying one commit onto a tree.
+type predictedStep struct {
+	tree      string
+	conflicts []string
+}
+
+// commitTrees holds a commit's tree and the tree of its first parent.
+type commitTrees struct {
+	tree, parentTree, parent string
+}
+
+// NewPredictor returns a Predictor with an empty cache.
+func NewPredictor() *Predictor {
+	return &Predictor{steps: map[[3]string]predictedStep{}, trees: map[string]commitTrees{}}
+}
+
+// Predict replays plan (newest first) onto base, or onto an empty tree when
+// base is empty, and returns the conflicting paths per commit hash for the
+// rows that would not apply cleanly. After a conflict it carries on as if
+// the commit had been resolved to its original content. Rows other than
+// commits are ignored, and label/reset/merge plans are not supported. The
+// git processes it starts are killed when ctx is cancelled.
+func (p *Predictor) Predict(ctx context.Context, base string, plan []CommitAction) (map[string][]string, error) {
+	var hashes []string
+	for _, ca := range plan {
+		if ca.Instruction.Command.TakesCommit() && ca.Instruction.Command != todo.Drop {
+			hashes = append(hashes, ca.Commit.Hash)
+		}
+	}
+	if err := p.loadTrees(ctx, hashes); err != nil {
+		return nil, err
+	}
+	tip, tipTree := base, emptyTree
+	if base != "" {
+		out, err := gitContext(ctx, "rev-parse", base+"^{tree}")
+		if err != nil {
+			return nil, err
+		}
+		tipTree = strings.TrimSpace(string(out))
+	}
+	res := map[string][]string{}
+	for i := len(plan) - 1; i >= 0; i-- {
+		ins := plan[i].Instruction
+		if ins.Command == todo.Merge {
+			return nil, errors.New("conflict prediction doesn't support merges")
+		}
+		if !ins.Command.TakesCommit() || ins.Command == todo.Drop {
+			continue
+		}
+		c := plan[i].Commit.Hash
+		p.mu.Lock()
+		t := p.trees[c]
+		p.mu.Unlock()
+		if tip != "" && tip == t.parent {
+			// Still on the original history: the commit applies as it is.
+			tip, tipTree = c, t.tree
+			continue
+		}
+		step, err := p.apply(ctx, t.parentTree, tipTree, t.tree)
+		if err != nil {
+			return nil, err
+		}
+		if len(step.conflicts) > 0 {
+			res[c] = step.conflicts
+			step.tree = t.tree
+		}
+		tip, tipTree = "", step.tree
+	}
+	return res, nil
+}
+
+// loadTrees looks up the trees of the commits and of their first parents.
+func (p *Predictor) loadTrees(ctx context.Context, hashes []string) error {
+	p.mu.Lock()
+	var missing []string
+	for _, h := range hashes {
+		if _, ok := p.trees[h]; !ok {
+			missing = append(missing, h)
+		}
+	}
+	p.mu.Unlock()
+	if len(missing) == 0 {
+		return nil
+	}
+	out, err := gitContext(ctx, append([]string{"log", "--no-walk=unsorted", "--format=%H %T %P"}, missing...)...)
+	if err != nil {
+		return err
+	}
+	found := map[string]commitTrees{}
+	var parents []string
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) < 2 {
+			continue
+		}
+		ct := commitTrees{tree: f[1], parentTree: emptyTree}
+		if len(f) > 2 {
+			ct.parent = f[2]
+			parents = append(parents, f[2])
+		}
+		found[f[0]] = ct
+	}
+	if len(parents) > 0 {
+		out, err := gitContext(ctx, append([]string{"log", "--no-walk=unsorted", "--format=%H %T"}, parents...)...)
+		if err != nil {
+			return err
+		}
+		parentTrees := map[string]string{}
+		for _, l := range strings.Split(string(out), "\n") {
+			if h, t, ok := strings.Cut(l, " "); ok {
+				parentTrees[h] = t
+			}
+		}
+		for h, ct := range found {
+			if ct.parent != "" {
+				ct.parentTree = parentTrees[ct.parent]
+				found[h] = ct
+			}
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for h, ct := range found {
+		p.trees[h] = ct
+	}
+	return nil
+}
+
+// apply cherry-picks the change from baseTree to commitTree onto tipTree.
+// git merge-tree picks the merge base itself, so both sides are wrapped in
+// throwaway commits sharing a parentless commit of baseTree.
+func (p *Predictor) apply(ctx context.Context, baseTree, tipTree, commitTree string) (predictedStep, error) {
+	key := [3]string{baseTree, tipTree, commitTree}
+	p.mu.Lock()
+	step, ok := p.steps[key]
+	p.mu.Unlock()
+	if ok {
+		return step, nil
+	}
+	if baseTree == tipTree {
+		step = predictedStep{tree: commitTree}
+	} else if baseTree == commitTree {
+		step = predictedStep{tree: tipTree}
+	} else {
+		base, err := commitTreeObject(ctx, baseTree)
+		if err != nil {
+			return step, err
+		}
+		ours, err := commitTreeObject(ctx, tipTree, base)
+		if err != nil {
+			return step, err
+		}
+		theirs, err := commitTreeObject(ctx, commitTree, base)
+		if err != nil {
+			return step, err
+		}
+		out, err := gitContext(ctx, "merge-tree", "--write-tree", "--name-only", "--no-messages", ours, theirs)
+		var ee *exec.ExitError
+		if err != nil && !(errors.As(err, &ee) && ee.ExitCode() == 1) {
+			return step, err
+		}
+		lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+		step.tree = lines[0]
+		seen := map[string]bool{}
+		for _, l := range lines[1:] {
+			if l == "" {
+				break
+			}
+			if !seen[l] {
+				seen[l] = true
+				step.conflicts = append(step.conflicts, l)
+			}
+		}
+	}
+	p.mu.Lock()
+	p.steps[key] = step
+	p.mu.Unlock()
+	return step, nil
+}
+
+// commitTreeObject writes a throwaway commit of tree with the given parents.
+func commitTreeObject(ctx context.Context, tree string, parents ...string) (string, error) {
+	args := []string{"-c", "user.name=rebasei-tui", "-c", "user.email=rebasei-tui@localhost", "commit-tree", "-m", "rebasei-tui prediction"}
+	for _, p := range parents {
+		args = append(args, "-p", p)
+	}
+	out, err := gitContext(ctx, append(args, tree)...)
+	return strings.TrimSpace(string(out)), err
+}
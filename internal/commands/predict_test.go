@@ -0,0 +1,63 @@
+package commands_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+func TestPredict(t *testing.T) {
+	r, base := newRepo(t)
+	one := r.Commit("one", "f.txt", "1\n")
+	two := r.Commit("two", "f.txt", "2\n")
+	r.Linear("A", "B")
+	head := r.Head()
+	p := commands.NewPredictor()
+
+	plan := planOf(t, base)
+	if got, err := p.Predict(context.Background(), base, plan); err != nil || len(got) != 0 {
+		t.Errorf("Predict for the original order = %v, %v; want no conflicts", got, err)
+	}
+
+	// Swapping the two edits of f conflicts on both; the rows after them
+	// still apply.
+	swapped := append([]commands.CommitAction(nil), plan...)
+	i, j := len(plan)-1, len(plan)-2
+	swapped[i], swapped[j] = swapped[j], swapped[i]
+	// A cancelled prediction stops and caches nothing it cut short.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Predict(ctx, base, swapped); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled Predict: err = %v, want context.Canceled", err)
+	}
+	got, err := p.Predict(context.Background(), base, swapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string][]string{one: {"f.txt"}, two: {"f.txt"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Predict for the swapped order = %v, want %v", got, want)
+	}
+
+	// Dropping the first edit instead conflicts only on the second.
+	dropped := append([]commands.CommitAction(nil), plan...)
+	dropped[i].Instruction.Command = todo.Drop
+	if got, err := p.Predict(context.Background(), base, dropped); err != nil || !reflect.DeepEqual(got, map[string][]string{two: {"f.txt"}}) {
+		t.Errorf("Predict with one dropped = %v, %v; want a conflict on two", got, err)
+	}
+
+	if r.Head() != head || r.Status() != nil {
+		t.Error("Predict changed the repository")
+	}
+}
+
+func TestPredictRefusesMerges(t *testing.T) {
+	_, base := newRepo(t)
+	plan := []commands.CommitAction{{Instruction: todo.Instruction{Command: todo.Merge, Option: "-C", Arg: "feature"}}}
+	if _, err := commands.NewPredictor().Predict(context.Background(), base, plan); err == nil {
+		t.Error("Predict of a merge plan succeeded")
+	}
+}
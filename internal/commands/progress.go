@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// RebaseState describes a rebase that git has stopped in the middle of.
+type RebaseState struct {
+	// Dir is git's state directory, .git/rebase-merge or .git/rebase-apply.
+	Dir string
+	// Apply is set for the apply backend, which keeps no editable todo.
+	Apply bool
+	// HeadName is the branch being rebased, or "detached HEAD".
+	HeadName string
+	// Onto is the full hash of the commit the rebase started on.
+	Onto string
+	// OrigHead is the full hash the branch pointed at before the rebase.
+	OrigHead string
+	// Done holds the instructions already run, newest first; Done[0] is the
+	// one git stopped at.
+	Done []CommitAction
+	// Todo holds the remaining instructions, newest first.
+	Todo []CommitAction
+}
+
+// TodoPath returns the path of the remaining todo, which git re-reads on
+// --continue. It is empty for the apply backend.
+func (s *RebaseState) TodoPath() string {
+	if s.Apply {
+		return ""
+	}
+	return filepath.Join(s.Dir, "git-rebase-todo")
+}
+
+// Branch returns the short name of the branch being rebased.
+func (s *RebaseState) Branch() string {
+	return strings.TrimPrefix(s.HeadName, "refs/heads/")
+}
+
+// InProgressRebase returns the state of the rebase in progress, or nil when
+// there is none. A "git am" session also lives in rebase-apply; it is not a
+// rebase and is ignored.
+func InProgressRebase(ctx context.Context) (*RebaseState, error) {
+	for _, name := range []string{"rebase-merge", "rebase-apply"} {
+		dir, err := gitPath(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			continue
+		}
+		s := &RebaseState{Dir: dir, Apply: name == "rebase-apply"}
+		if s.Apply && exists(filepath.Join(dir, "applying")) {
+			return nil, nil
+		}
+		s.HeadName = readStateFile(dir, "head-name")
+		s.Onto = readStateFile(dir, "onto")
+		s.OrigHead = readStateFile(dir, "orig-head")
+		if s.Apply {
+			return s, nil
+		}
+		if s.Done, err = ReadTodo(ctx, filepath.Join(dir, "done")); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if s.Todo, err = ReadTodo(ctx, s.TodoPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, nil
+}
+
+// RebaseStep returns the command that resumes or ends the rebase in
+// progress; flag is "--continue", "--skip" or "--abort". It is meant to run
+// attached to the terminal so git can open an editor or report conflicts.
+func RebaseStep(flag string) *exec.Cmd {
+	return gitCommand("rebase", flag)
+}
+
+// stateSignOption returns how git signs the commits of the rebase whose
+// state is in dir: the -S option it was started with, or --no-gpg-sign.
+func stateSignOption(dir string) string {
+	if opt := readStateFile(dir, "gpg_sign_opt"); opt != "" {
+		return opt
+	}
+	return signOption(false)
+}
+
+// readStateFile returns the trimmed content of a file in git's rebase state
+// directory, or "" if it is missing.
+func readStateFile(dir, name string) string {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
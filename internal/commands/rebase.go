@@ -5,65 +5,84 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strconv"
-	"strings"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
 )
 
+// CommitAction is one row of a rebase plan. Rows for pick-like instructions
+// also carry the commit's details; other rows (exec, break, ...) only the
+// instruction.
 type CommitAction struct {
-	Commit Commit
-	Action string // pick, squash, fixup, edit, drop
+	Commit      Commit
+	Instruction todo.Instruction
+	// Message is the new commit message for a reword row. When set the
+	// commit is picked and amended with it instead of git opening $EDITOR.
+	Message string
+}
+
+// RebaseOptions configures InteractiveRebase.
+type RebaseOptions struct {
+	// Base is the full hash of the commit to rebase onto, as returned by
+	// ResolveBase or WindowBase; when empty the rebase starts from the root.
+	Base string
+	// RebaseMerges passes --rebase-merges for plans built by MergesPlan.
+	RebaseMerges bool
+	// UpdateRefs passes --update-refs so the plan's update-ref rows move
+	// the other branches of a stack along with the rewritten commits.
+	UpdateRefs bool
+	// Autostash passes --autostash: local changes are stashed before the
+	// rebase and applied again once it is over.
+	Autostash bool
+	// Sign passes -S so every rewritten commit is signed; otherwise
+	// --no-gpg-sign overrides commit.gpgSign.
+	Sign bool
 }
 
-func RunInteractiveRebase(list []CommitAction) error {
+// InteractiveRebase prepares "git rebase -i" with the given plan (newest
+// first). The command still needs the terminal attached, since git may stop
+// or open an editor; cleanup removes the temporary todo once it has run.
+func InteractiveRebase(opts RebaseOptions, list []CommitAction) (cmd *exec.Cmd, cleanup func(), err error) {
 	if len(list) == 0 {
-		return fmt.Errorf("no commits to rebase")
-	}
-	// Build todo in chronological order (oldest first) so squash/fixup have a previous commit.
-	todo := ""
-	for i := len(list) - 1; i >= 0; i-- {
-		ca := list[i]
-		if ca.Action == "" {
-			ca.Action = "pick"
-		}
-		todo += fmt.Sprintf("%s %s %s\n", ca.Action, ca.Commit.Hash, ca.Commit.Subject)
+		return nil, nil, fmt.Errorf("no commits to rebase")
 	}
+	todoText := todo.Format(instructions(list, signOption(opts.Sign)))
 
 	tmpDir, err := os.MkdirTemp("", "rebasei-tui-*")
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	defer os.RemoveAll(tmpDir)
+	cleanup = func() { os.RemoveAll(tmpDir) }
 	todoPath := filepath.Join(tmpDir, "todo.txt")
-	if err := os.WriteFile(todoPath, []byte(todo), 0o644); err != nil {
-		return err
+	if err := os.WriteFile(todoPath, []byte(todoText), 0o644); err != nil {
+		cleanup()
+		return nil, nil, err
 	}
 
 	scriptPath := filepath.Join(tmpDir, "seq_editor.sh")
 	script := fmt.Sprintf("#!/bin/sh\ncat '%s' > \"$1\"\n", todoPath)
 	if err := os.WriteFile(scriptPath, []byte(script), 0o755); err != nil {
-		return err
+		cleanup()
+		return nil, nil, err
 	}
 
-	n := len(list)
-	// Use --root when there aren't enough ancestors for HEAD~n
-	countCmd := exec.Command("git", "rev-list", "--count", "HEAD")
-	countCmd.Env = append(os.Environ(), "GIT_PAGER=cat")
-	out, _ := countCmd.Output()
-	total := 0
-	if len(out) > 0 {
-		if v, err := strconv.Atoi(strings.TrimSpace(string(out))); err == nil {
-			total = v
-		}
+	target := opts.Base
+	if target == "" {
+		target = "--root"
+	}
+	args := []string{"-c", "sequence.editor=" + scriptPath, "rebase", "-i"}
+	if opts.RebaseMerges {
+		args = append(args, "--rebase-merges")
+	}
+	if opts.UpdateRefs {
+		args = append(args, "--update-refs")
+	}
+	if opts.Autostash {
+		args = append(args, "--autostash")
 	}
-	var cmd *exec.Cmd
-	if total > 0 && n >= total {
-		cmd = exec.Command("git", "-c", "sequence.editor="+scriptPath, "rebase", "-i", "--root")
+	if opts.Sign {
+		args = append(args, "-S")
 	} else {
-		base := fmt.Sprintf("HEAD~%d", n)
-		cmd = exec.Command("git", "-c", "sequence.editor="+scriptPath, "rebase", "-i", base)
+		args = append(args, "--no-gpg-sign")
 	}
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return gitCommand(append(args, target)...), cleanup, nil
 }
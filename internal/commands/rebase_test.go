@@ -0,0 +1,114 @@
+package commands_test
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+// run runs a git command prepared for the terminal, failing the test with
+// its output unless it succeeds or fails as want says.
+func run(t *testing.T, cmd *exec.Cmd, wantErr bool) {
+	t.Helper()
+	out, err := cmd.CombinedOutput()
+	if (err != nil) != wantErr {
+		t.Fatalf("%s: error %v, want error %v\n%s", strings.Join(cmd.Args, " "), err, wantErr, out)
+	}
+}
+
+// interactiveRebase runs the plan with git rebase -i as the TUI does.
+func interactiveRebase(t *testing.T, opts commands.RebaseOptions, plan []commands.CommitAction, wantErr bool) {
+	t.Helper()
+	cmd, cleanup, err := commands.InteractiveRebase(opts, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	run(t, cmd, wantErr)
+}
+
+func TestInteractiveRebaseReword(t *testing.T) {
+	r, base := newRepo(t)
+	r.Linear("A", "B", "C")
+	plan := planOf(t, base)
+	b := row(t, plan, "B")
+	b.Instruction.Command = todo.Reword
+	b.Message = "B, reworded\n\nIt's quoted: 'single' and \"double\"; $HOME `x`."
+
+	interactiveRebase(t, commands.RebaseOptions{Base: base}, plan, false)
+
+	if got, want := r.Subjects(base, "HEAD"), []string{"C", "B, reworded", "A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("subjects = %q, want %q", got, want)
+	}
+	if got := r.Git("log", "-1", "--format=%B", "HEAD~"); got != b.Message {
+		t.Errorf("reworded message = %q, want %q", got, b.Message)
+	}
+}
+
+func TestInteractiveRebaseExec(t *testing.T) {
+	r, base := newRepo(t)
+	r.Linear("A", "B")
+	plan := planOf(t, base)
+	// An exec row right after A, which is below B in the newest-first plan.
+	echo := commands.CommitAction{Instruction: todo.Instruction{Command: todo.Exec, Arg: "git log -1 --format=%s > exec.out"}}
+	plan = append(plan[:1], append([]commands.CommitAction{echo}, plan[1:]...)...)
+
+	interactiveRebase(t, commands.RebaseOptions{Base: base}, plan, false)
+
+	if got := r.Read("exec.out"); got != "A\n" {
+		t.Errorf("exec.out = %q, want the exec to run after A", got)
+	}
+	if got, want := r.Subjects(base, "HEAD"), []string{"B", "A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("subjects = %q, want %q", got, want)
+	}
+}
+
+func TestInteractiveRebaseBreak(t *testing.T) {
+	r, base := newRepo(t)
+	r.Linear("A", "B")
+	plan := planOf(t, base)
+	row(t, plan, "A").Instruction.Command = todo.Drop
+	brk := commands.CommitAction{Instruction: todo.Instruction{Command: todo.Break}}
+	// Break before B is picked.
+	plan = append(plan[:1], append([]commands.CommitAction{brk}, plan[1:]...)...)
+
+	interactiveRebase(t, commands.RebaseOptions{Base: base}, plan, false)
+
+	s, err := commands.InProgressRebase(context.Background())
+	if err != nil || s == nil {
+		t.Fatalf("InProgressRebase = %v, %v; want the stopped rebase", s, err)
+	}
+	if got := s.Done[0].Instruction.Command; got != todo.Break {
+		t.Errorf("stopped at %q, want break", got)
+	}
+	if len(s.Todo) != 1 || s.Todo[0].Commit.Subject != "B" {
+		t.Errorf("remaining todo = %+v, want pick B", s.Todo)
+	}
+	if r.Head() != base {
+		t.Errorf("HEAD = %s, want the base: A is dropped", r.Head())
+	}
+
+	run(t, commands.RebaseStep("--continue"), false)
+	if s, err := commands.InProgressRebase(context.Background()); err != nil || s != nil {
+		t.Fatalf("InProgressRebase after --continue = %v, %v; want none", s, err)
+	}
+	if got, want := r.Subjects(base, "HEAD"), []string{"B"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("subjects = %q, want %q", got, want)
+	}
+}
+
+func TestInteractiveRebaseNeedsPlan(t *testing.T) {
+	_, base := newRepo(t)
+	if _, _, err := commands.InteractiveRebase(commands.RebaseOptions{Base: base}, nil); err == nil {
+		t.Error("InteractiveRebase with an empty plan succeeded")
+	}
+	if _, err := commands.RebaseInMemory(commands.RebaseOptions{Base: base}, nil); !errors.Is(err, commands.ErrNeedsStop) {
+		t.Errorf("RebaseInMemory with an empty plan: %v, want ErrNeedsStop", err)
+	}
+}
@@ -0,0 +1,45 @@
+package commands_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/gittest"
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+// newRepo returns a repository the commands run in, with a first commit
+// the tests rebase onto.
+func newRepo(t *testing.T) (r *gittest.Repo, base string) {
+	t.Helper()
+	r = gittest.New(t)
+	r.Use()
+	return r, r.Commit("base", "base.txt", "base\n")
+}
+
+// planOf returns the plan picking base..HEAD, newest first.
+func planOf(t *testing.T, base string) []commands.CommitAction {
+	t.Helper()
+	commits, err := commands.ListRange(context.Background(), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := make([]commands.CommitAction, len(commits))
+	for i, c := range commits {
+		plan[i] = commands.CommitAction{Commit: c, Instruction: todo.Instruction{Command: todo.Pick, Commit: c.Hash, Text: c.Subject}}
+	}
+	return plan
+}
+
+// row returns the row of plan for the commit with subject.
+func row(t *testing.T, plan []commands.CommitAction, subject string) *commands.CommitAction {
+	t.Helper()
+	for i := range plan {
+		if plan[i].Commit.Subject == subject {
+			return &plan[i]
+		}
+	}
+	t.Fatalf("no row for %q", subject)
+	return nil
+}
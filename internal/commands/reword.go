@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+// rewordExec returns an exec instruction that replaces HEAD's message with
+// msg. The message is embedded in the command itself, so it survives stops
+// and needs no temporary files, and git never opens $EDITOR for it. Hooks
+// run as for git's own reword, so a commit-msg hook can add a Change-Id.
+// sign is the signing option of the rebase, see signOption.
+func rewordExec(msg, sign string) todo.Instruction {
+	lines := strings.Split(strings.TrimRight(msg, "\n"), "\n")
+	quoted := make([]string, len(lines))
+	for i, l := range lines {
+		quoted[i] = shellQuote(l)
+	}
+	opts := "-q"
+	if sign != "" {
+		opts += " " + shellQuote(sign)
+	}
+	cmd := "printf '%s\\n' " + strings.Join(quoted, " ") +
+		" | git commit --amend --only --allow-empty --cleanup=whitespace " + opts + " -F -"
+	return todo.Instruction{Command: todo.Exec, Arg: cmd}
+}
+
+// signOption returns the git commit option that signs commits, or that
+// keeps them unsigned whatever commit.gpgSign says.
+func signOption(sign bool) string {
+	if sign {
+		return "-S"
+	}
+	return "--no-gpg-sign"
+}
+
+// shellQuote quotes s for POSIX sh using single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
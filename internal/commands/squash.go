@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+	"github.com/fredrikmwold/rebasei-tui/internal/trailers"
+)
+
+// trailerRulePrefix is where the trailer rules are configured, one per
+// key, e.g. "git config rebasei.trailer.change-id first".
+const trailerRulePrefix = "rebasei.trailer."
+
+// TrailerRules returns the rules for merging the trailers of squashed
+// commits: trailers.DefaultRules with the configured ones on top. Values
+// that aren't a rule are ignored.
+func TrailerRules(ctx context.Context) trailers.Rules {
+	rules := trailers.DefaultRules()
+	out, err := gitContext(ctx, "config", "--get-regexp", `^rebasei\.trailer\.`)
+	if err != nil {
+		// None configured.
+		return rules
+	}
+	for _, l := range strings.Split(string(out), "\n") {
+		name, value, _ := strings.Cut(l, " ")
+		key, ok := strings.CutPrefix(name, trailerRulePrefix)
+		if !ok || key == "" {
+			continue
+		}
+		if r, ok := trailers.ParseRule(value); ok {
+			rules[strings.ToLower(key)] = r
+		}
+	}
+	return rules
+}
+
+// ComposeSquash builds the message of a squash group, given oldest first:
+// the commit the others meld into, then its squash and fixup rows. Like
+// git, it keeps the text of the first commit and of the squashes, and
+// "fixup -C"/"-c" replaces the text gathered so far; unlike git, the
+// trailers of every commit, fixups included, end up in one block merged
+// by rules. merged reports whether that block differs from the trailers of
+// the message git would keep for a group without squashes.
+func ComposeSquash(ctx context.Context, rules trailers.Rules, group []CommitAction) (msg string, merged bool, err error) {
+	var texts []string
+	var lists [][]trailers.Trailer
+	var kept []trailers.Trailer
+	for i, ca := range group {
+		m := ca.Message
+		if m == "" || ca.Instruction.Command != todo.Reword {
+			if m, err = CommitMessage(ctx, ca.Commit.Hash); err != nil {
+				return "", false, err
+			}
+		}
+		if ca.Instruction.Command == todo.Fixup && ca.Instruction.Option != "" {
+			m = amendMessage(m)
+		}
+		text, ts := trailers.Split(m)
+		lists = append(lists, ts)
+		switch {
+		case i == 0, ca.Instruction.Option != "":
+			texts, kept = []string{text}, ts
+		case ca.Instruction.Command == todo.Squash:
+			texts = append(texts, squashText(text))
+		}
+	}
+	all := trailers.Merge(rules, lists...)
+	var parts []string
+	for _, t := range texts {
+		if t != "" {
+			parts = append(parts, t)
+		}
+	}
+	return trailers.Join(strings.Join(parts, "\n\n"), all), !slices.Equal(all, kept), nil
+}
+
+// squashText drops the "squash! <subject>" line git's autosquash commits
+// start with, which git comments out of the squashed message too.
+func squashText(text string) string {
+	if !strings.HasPrefix(text, "squash! ") {
+		return text
+	}
+	_, rest, _ := strings.Cut(text, "\n")
+	return strings.TrimLeft(rest, "\n")
+}
+
+// composedRows marks the squash and fixup rows of the plan (newest first)
+// that belong to a group whose message was composed in the TUI. The
+// group's newest row carries the message; drop and update-ref rows don't
+// end a group.
+func composedRows(list []CommitAction) []bool {
+	res := make([]bool, len(list))
+	in := false
+	for i, ca := range list {
+		switch ca.Instruction.Command {
+		case todo.Squash, todo.Fixup:
+			if ca.Message != "" {
+				in = true
+			}
+			res[i] = in
+		case todo.Drop, todo.UpdateRef:
+		default:
+			in = false
+		}
+	}
+	return res
+}
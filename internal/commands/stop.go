@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ShortStatus returns the working tree status in "git status --short" form,
+// one line per changed path.
+func ShortStatus(ctx context.Context) ([]string, error) {
+	out, err := gitContext(ctx, "-c", "color.status=false", "status", "--short")
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, l := range strings.Split(string(out), "\n") {
+		if strings.TrimSpace(l) != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines, nil
+}
+
+// HeadCommit returns the commit HEAD points at.
+func HeadCommit(ctx context.Context) (Commit, error) {
+	out, err := gitContext(ctx, "log", "-1", "--date=short", "--decorate=full", logFormat, "HEAD")
+	if err != nil {
+		return Commit{}, err
+	}
+	cs, err := parseLog(out)
+	if err != nil || len(cs) == 0 {
+		return Commit{}, err
+	}
+	return cs[0], nil
+}
+
+// StageAll stages every change in the working tree, like "git add -A".
+func StageAll(ctx context.Context) error {
+	_, err := gitContext(ctx, "add", "-A")
+	return err
+}
+
+// AmendStaged folds the staged changes into HEAD, keeping its message. The
+// commit is signed if the rebase in progress signs its commits.
+func AmendStaged(ctx context.Context) error {
+	args := []string{"commit", "--amend", "--no-edit", "--allow-empty", "-q"}
+	if s, err := InProgressRebase(ctx); err == nil && s != nil {
+		args = append(args, stateSignOption(s.Dir))
+	}
+	_, err := gitContext(ctx, args...)
+	return err
+}
+
+// ResetToParent moves HEAD to its parent and keeps the commit's changes in
+// the working tree, which is how a commit stopped at with "edit" is split.
+func ResetToParent(ctx context.Context) error {
+	_, err := gitContext(ctx, "reset", "-q", "HEAD~")
+	return err
+}
+
+// ShellCmd returns the user's shell, for work git can't be asked to do from
+// the TUI. It starts in dir, which like for git's exec is meant to be the
+// top of the working tree (RepoDir).
+func ShellCmd(dir string) *exec.Cmd {
+	sh := os.Getenv("SHELL")
+	if sh == "" {
+		sh = "/bin/sh"
+	}
+	cmd := exec.Command(sh)
+	cmd.Dir = dir
+	cmd.Env = runnerEnv()
+	return cmd
+}
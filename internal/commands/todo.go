@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+// CommentChar returns git's core.commentChar, defaulting to "#".
+// "auto" also maps to "#" since git only varies it for commit messages.
+func CommentChar(ctx context.Context) string {
+	out, err := gitContext(ctx, "config", "--get", "core.commentChar")
+	c := strings.TrimSpace(string(out))
+	if err != nil || c == "" || c == "auto" {
+		return "#"
+	}
+	return c
+}
+
+// ReadTodo parses a git-rebase-todo file into a plan, newest first like
+// the commits ListRange and CommitStream return. Commit instructions are
+// resolved to full commit details;
+// other instructions (exec, break, label, ...) are kept as they are.
+// Comments and blank lines are dropped.
+func ReadTodo(ctx context.Context, path string) ([]CommitAction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	list, err := todo.Parse(f, CommentChar(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var plan []CommitAction
+	var hashes []string
+	for _, ins := range list {
+		if ins.Command == todo.Comment {
+			continue
+		}
+		ca := CommitAction{Instruction: ins}
+		if ins.Command.TakesCommit() {
+			ca.Commit = Commit{Hash: ins.Commit, HashShort: ins.Commit, Subject: ins.Text}
+			hashes = append(hashes, ins.Commit)
+		}
+		plan = append(plan, ca)
+	}
+	if len(hashes) > 0 {
+		out, err := gitContext(ctx, append([]string{"log", "--no-walk=unsorted", "--date=short", "--decorate=full", logFormat}, hashes...)...)
+		if err != nil {
+			return nil, err
+		}
+		details, err := parseLog(out)
+		if err != nil {
+			return nil, err
+		}
+		for i := range plan {
+			if !plan[i].Instruction.Command.TakesCommit() {
+				continue
+			}
+			for _, c := range details {
+				if strings.HasPrefix(c.Hash, plan[i].Instruction.Commit) {
+					plan[i].Commit = c
+					break
+				}
+			}
+		}
+	}
+	// Reverse into newest-first order.
+	for i, j := 0, len(plan)-1; i < j; i, j = i+1, j-1 {
+		plan[i], plan[j] = plan[j], plan[i]
+	}
+	return plan, nil
+}
+
+// WriteTodo writes the plan (newest first) to path in git-rebase-todo format.
+// An empty plan truncates the file, which makes git abort the rebase.
+// Messages set in the TUI are signed like the rest of the rebase, as git
+// recorded next to the todo.
+func WriteTodo(path string, list []CommitAction) error {
+	sign := stateSignOption(filepath.Dir(path))
+	return os.WriteFile(path, []byte(todo.Format(instructions(list, sign))), 0o644)
+}
+
+// instructions returns the plan's todo instructions in chronological order
+// (oldest first) so squash/fixup have a previous commit.
+func instructions(list []CommitAction, sign string) []todo.Instruction {
+	res := make([]todo.Instruction, 0, len(list))
+	composed := composedRows(list)
+	for i := len(list) - 1; i >= 0; i-- {
+		ins := list[i].Instruction
+		if ins.Command == todo.Reword && list[i].Message != "" {
+			ins.Command = todo.Pick
+			res = append(res, ins, rewordExec(list[i].Message, sign))
+			continue
+		}
+		if composed[i] {
+			// The group's message is set once its last row has run, so git
+			// needn't open an editor for it.
+			ins.Command, ins.Option = todo.Fixup, ""
+			res = append(res, ins)
+			if list[i].Message != "" {
+				res = append(res, rewordExec(list[i].Message, sign))
+			}
+			continue
+		}
+		res = append(res, ins)
+	}
+	return res
+}
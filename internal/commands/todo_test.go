@@ -0,0 +1,125 @@
+package commands_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+// readFile returns the content of the file at path.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// rows describes a plan oldest first, one "command[ option] subject" per
+// commit row and the instruction itself for other rows.
+func rows(plan []commands.CommitAction) []string {
+	res := make([]string, len(plan))
+	for i, ca := range plan {
+		ins := ca.Instruction
+		s := ins.String()
+		if ins.Command.TakesCommit() {
+			s = string(ins.Command)
+			if ins.Option != "" {
+				s += " " + ins.Option
+			}
+			s += " " + ca.Commit.Subject
+		}
+		res[len(plan)-1-i] = s
+	}
+	return res
+}
+
+func TestWriteAndReadTodo(t *testing.T) {
+	r, base := newRepo(t)
+	hashes := r.Linear("A", "B", "C")
+	plan := planOf(t, base)
+	row(t, plan, "A").Instruction.Command = todo.Drop
+	brk := commands.CommitAction{Instruction: todo.Instruction{Command: todo.Break}}
+	plan = append(plan[:1], append([]commands.CommitAction{brk}, plan[1:]...)...)
+
+	path := filepath.Join(t.TempDir(), "git-rebase-todo")
+	if err := commands.WriteTodo(path, plan); err != nil {
+		t.Fatal(err)
+	}
+	want := "drop " + hashes[0] + " A\npick " + hashes[1] + " B\nbreak\npick " + hashes[2] + " C\n"
+	if got := readFile(t, path); got != want {
+		t.Errorf("todo =\n%s\nwant\n%s", got, want)
+	}
+
+	// git writes short hashes and comments; reading resolves the commits.
+	short := "# Rebase\n" + strings.ReplaceAll(want, hashes[1], hashes[1][:7]) + "\n# Commands:\n"
+	if err := os.WriteFile(path, []byte(short), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := commands.ReadTodo(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := rows(got); !reflect.DeepEqual(r, []string{"drop A", "pick B", "break", "pick C"}) {
+		t.Errorf("ReadTodo = %q", r)
+	}
+	if b := row(t, got, "B"); b.Commit.Hash != hashes[1] || b.Instruction.Commit != hashes[1][:7] {
+		t.Errorf("B = %s from %s, want %s from the short hash", b.Commit.Hash, b.Instruction.Commit, hashes[1])
+	}
+}
+
+func TestReadTodoCommentChar(t *testing.T) {
+	r, _ := newRepo(t)
+	r.Linear("A")
+	r.Git("config", "core.commentChar", ";")
+	path := filepath.Join(t.TempDir(), "git-rebase-todo")
+	if err := os.WriteFile(path, []byte("pick "+r.Head()+" A\n; Commands:\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := commands.ReadTodo(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rows(plan); !reflect.DeepEqual(got, []string{"pick A"}) {
+		t.Errorf("ReadTodo = %q", got)
+	}
+}
+
+func TestWriteTodoReword(t *testing.T) {
+	r, base := newRepo(t)
+	r.Linear("A")
+	plan := planOf(t, base)
+	plan[0].Instruction.Command = todo.Reword
+	plan[0].Message = "A, reworded"
+	dir := t.TempDir()
+	path := filepath.Join(dir, "git-rebase-todo")
+
+	for _, tt := range []struct{ signOpt, want string }{
+		{"", "--no-gpg-sign"},
+		{"-S", "-S"},
+	} {
+		if tt.signOpt != "" {
+			// git records how the rebase signs next to the todo.
+			if err := os.WriteFile(filepath.Join(dir, "gpg_sign_opt"), []byte(tt.signOpt+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := commands.WriteTodo(path, plan); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(readFile(t, path), "\n"), "\n")
+		if len(lines) != 2 || !strings.HasPrefix(lines[0], "pick ") || !strings.HasPrefix(lines[1], "exec ") {
+			t.Fatalf("todo = %q, want a pick and an exec amending it", lines)
+		}
+		if !strings.Contains(lines[1], "'A, reworded'") || !strings.Contains(lines[1], " '"+tt.want+"' ") {
+			t.Errorf("gpg_sign_opt %q: exec = %q, want the message and %s", tt.signOpt, lines[1], tt.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Package gittest builds throwaway git repositories with scripted
+// histories, so the rebase commands can be run end to end against them.
+package gittest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+)
+
+// epoch is the date of the first commit; each later git command runs one
+// minute after the previous one, so hashes are the same on every run.
+const epoch = 1700000000
+
+// Repo is a temporary repository. Its methods fail the test on any error.
+type Repo struct {
+	t testing.TB
+	// Dir is the top of the working tree.
+	Dir string
+	// Runner runs git in the repository, isolated from the user's and the
+	// system's configuration, with a fixed identity.
+	Runner commands.Exec
+	clock  int64
+}
+
+// New creates an empty repository on branch main in a temporary directory
+// that is removed when the test ends.
+func New(t testing.TB) *Repo {
+	t.Helper()
+	dir := t.TempDir()
+	r := &Repo{t: t, Dir: dir, clock: epoch}
+	r.Runner = commands.Exec{Dir: dir, Env: []string{
+		"GIT_CONFIG_GLOBAL=" + os.DevNull,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=A U Thor",
+		"GIT_AUTHOR_EMAIL=author@example.com",
+		"GIT_COMMITTER_NAME=C O Mitter",
+		"GIT_COMMITTER_EMAIL=committer@example.com",
+		"GIT_EDITOR=false",
+	}}
+	r.Git("init", "-q", "-b", "main")
+	return r
+}
+
+// Use makes the commands package run git in this repository until the
+// test ends.
+func (r *Repo) Use() {
+	commands.SetRunner(r.Runner)
+	r.t.Cleanup(func() { commands.SetRunner(commands.Exec{}) })
+}
+
+// Git runs git in the repository and returns its trimmed stdout.
+func (r *Repo) Git(args ...string) string {
+	r.t.Helper()
+	date := fmt.Sprintf("@%d +0000", r.clock)
+	r.clock += 60
+	out, err := r.Runner.Run(context.Background(),
+		[]string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date}, "", args...)
+	if err != nil {
+		r.t.Fatalf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Write writes content to the file at path, relative to the top of the
+// working tree, creating directories as needed.
+func (r *Repo) Write(path, content string) {
+	r.t.Helper()
+	full := filepath.Join(r.Dir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// Read returns the content of the file at path in the working tree.
+func (r *Repo) Read(path string) string {
+	r.t.Helper()
+	b, err := os.ReadFile(filepath.Join(r.Dir, path))
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return string(b)
+}
+
+// Commit writes files, given as path and content pairs, and commits them
+// with msg. It returns the new commit's full hash.
+func (r *Repo) Commit(msg string, files ...string) string {
+	r.t.Helper()
+	if len(files)%2 != 0 {
+		r.t.Fatalf("Commit(%q): files must be path and content pairs", msg)
+	}
+	for i := 0; i < len(files); i += 2 {
+		r.Write(files[i], files[i+1])
+		r.Git("add", "--", files[i])
+	}
+	r.Git("commit", "-q", "--allow-empty", "-m", msg)
+	return r.Head()
+}
+
+// Linear commits one change per subject, oldest first, each adding a line
+// to a file named after its position, and returns the hashes in the same
+// order. "fixup! X", "squash! X" and "amend! X" subjects instead change
+// the file of the earlier commit X, so they apply cleanly once moved next
+// to it.
+func (r *Repo) Linear(subjects ...string) []string {
+	r.t.Helper()
+	files := map[string]string{}
+	hashes := make([]string, len(subjects))
+	for i, s := range subjects {
+		path := fmt.Sprintf("file%d.txt", i+1)
+		for _, prefix := range []string{"fixup! ", "squash! ", "amend! "} {
+			if target, ok := strings.CutPrefix(s, prefix); ok && files[target] != "" {
+				path = files[target]
+			}
+		}
+		if _, ok := files[s]; !ok {
+			files[s] = path
+		}
+		content := ""
+		if b, err := os.ReadFile(filepath.Join(r.Dir, path)); err == nil {
+			content = string(b)
+		}
+		hashes[i] = r.Commit(s, path, content+s+"\n")
+	}
+	return hashes
+}
+
+// Branch creates or moves branch name to rev.
+func (r *Repo) Branch(name, rev string) {
+	r.t.Helper()
+	r.Git("branch", "-f", name, rev)
+}
+
+// Checkout switches to rev, a branch or a commit to detach at.
+func (r *Repo) Checkout(rev string) {
+	r.t.Helper()
+	r.Git("checkout", "-q", rev)
+}
+
+// Merge merges rev into the current branch with a merge commit and returns
+// its hash.
+func (r *Repo) Merge(rev, msg string) string {
+	r.t.Helper()
+	r.Git("merge", "-q", "--no-ff", "-m", msg, rev)
+	return r.Head()
+}
+
+// Head returns the full hash of HEAD.
+func (r *Repo) Head() string {
+	r.t.Helper()
+	return r.Git("rev-parse", "HEAD")
+}
+
+// Rev resolves rev to a full hash.
+func (r *Repo) Rev(rev string) string {
+	r.t.Helper()
+	return r.Git("rev-parse", "--verify", rev)
+}
+
+// Subjects returns the subjects of the first-parent history of rev back to
+// (excluding) base, newest first; an empty base means the whole history.
+func (r *Repo) Subjects(base, rev string) []string {
+	r.t.Helper()
+	if base != "" {
+		rev = base + ".." + rev
+	}
+	out := r.Git("log", "--first-parent", "--format=%s", rev)
+	if out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
+
+// Status returns the "git status --short" lines of the working tree.
+func (r *Repo) Status() []string {
+	r.t.Helper()
+	out := r.Git("status", "--short")
+	if out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
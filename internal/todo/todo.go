@@ -0,0 +1,265 @@
+// Package todo parses and writes git's interactive rebase todo format
+// (the git-rebase-todo file handed to the sequence editor).
+package todo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Command is a rebase todo instruction keyword.
+type Command string
+
+const (
+	Pick      Command = "pick"
+	Reword    Command = "reword"
+	Edit      Command = "edit"
+	Squash    Command = "squash"
+	Fixup     Command = "fixup"
+	Exec      Command = "exec"
+	Break     Command = "break"
+	Drop      Command = "drop"
+	Label     Command = "label"
+	Reset     Command = "reset"
+	Merge     Command = "merge"
+	UpdateRef Command = "update-ref"
+	Noop      Command = "noop"
+	// Comment is a comment or blank line; its Text holds the line verbatim.
+	Comment Command = ""
+)
+
+// abbrevs maps commands to the one-letter forms git accepts.
+var abbrevs = map[Command]string{
+	Pick: "p", Reword: "r", Edit: "e", Squash: "s", Fixup: "f", Exec: "x",
+	Break: "b", Drop: "d", Label: "l", Reset: "t", Merge: "m", UpdateRef: "u",
+}
+
+var commands = func() map[string]Command {
+	m := map[string]Command{string(Noop): Noop}
+	for c, a := range abbrevs {
+		m[string(c)] = c
+		m[a] = c
+	}
+	return m
+}()
+
+// ParseCommand returns the command for a full or abbreviated keyword.
+func ParseCommand(word string) (Command, bool) {
+	c, ok := commands[word]
+	return c, ok
+}
+
+// Abbrev returns the one-letter form of c, or c itself when it has none.
+func (c Command) Abbrev() string {
+	if a, ok := abbrevs[c]; ok {
+		return a
+	}
+	return string(c)
+}
+
+// TakesCommit reports whether c operates on a single commit.
+func (c Command) TakesCommit() bool {
+	switch c {
+	case Pick, Reword, Edit, Squash, Fixup, Drop:
+		return true
+	}
+	return false
+}
+
+// Instruction is one line of a todo list.
+type Instruction struct {
+	Command Command
+	// Abbrev writes the command using its one-letter form.
+	Abbrev bool
+	// Option is "-C" or "-c" for fixup and merge.
+	Option string
+	// Commit is the commit for pick-like commands and the original merge
+	// commit for merge -C/-c.
+	Commit string
+	// Arg is the exec command line, the label for label/reset, the label(s)
+	// to merge, or the ref for update-ref.
+	Arg string
+	// Text is the trailing text: the subject after a commit, the "# oneline"
+	// comment of reset/merge, or the whole line for a Comment.
+	Text string
+
+	// raw and rawKey let String reproduce the parsed line byte for byte as
+	// long as the instruction has not been modified.
+	raw, rawKey string
+}
+
+// String renders the instruction as a todo line without a trailing newline.
+func (i Instruction) String() string {
+	s := i.format()
+	if i.raw != "" && s == i.rawKey {
+		return i.raw
+	}
+	return s
+}
+
+func (i Instruction) format() string {
+	if i.Command == Comment {
+		return i.Text
+	}
+	word := string(i.Command)
+	if i.Abbrev {
+		word = i.Command.Abbrev()
+	}
+	parts := []string{word}
+	if i.Option != "" {
+		parts = append(parts, i.Option)
+	}
+	if i.Commit != "" {
+		parts = append(parts, i.Commit)
+	}
+	if i.Arg != "" {
+		parts = append(parts, i.Arg)
+	}
+	if i.Text != "" {
+		parts = append(parts, i.Text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseError reports a malformed todo line.
+type ParseError struct {
+	Line int // 1-based line number
+	Text string
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("todo line %d: %s: %q", e.Line, e.Msg, e.Text)
+}
+
+// Parse reads a todo list. commentChar is git's core.commentChar (usually
+// "#"); lines starting with it, and blank lines, become Comment instructions
+// so that writing the result back reproduces the input exactly.
+func Parse(r io.Reader, commentChar string) ([]Instruction, error) {
+	if commentChar == "" {
+		commentChar = "#"
+	}
+	var list []Instruction
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	n := 0
+	for s.Scan() {
+		n++
+		ins, err := ParseLine(s.Text(), commentChar)
+		if err != nil {
+			if pe, ok := err.(*ParseError); ok {
+				pe.Line = n
+			}
+			return nil, err
+		}
+		list = append(list, ins)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ParseLine parses a single todo line. See Parse for commentChar.
+func ParseLine(line, commentChar string) (Instruction, error) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, commentChar) {
+		return Instruction{Command: Comment, Text: line}, nil
+	}
+	word, rest := cut(trimmed)
+	cmd, ok := ParseCommand(word)
+	if !ok {
+		return Instruction{}, &ParseError{Text: line, Msg: "unknown command " + word}
+	}
+	ins := Instruction{Command: cmd, Abbrev: word != string(cmd)}
+	fail := func(msg string) (Instruction, error) {
+		return Instruction{}, &ParseError{Text: line, Msg: msg}
+	}
+	switch cmd {
+	case Break, Noop:
+		if rest != "" {
+			return fail(string(cmd) + " takes no arguments")
+		}
+	case Exec:
+		if rest == "" {
+			return fail("missing command")
+		}
+		ins.Arg = rest
+	case Label, UpdateRef:
+		if rest == "" {
+			return fail("missing " + argName(cmd))
+		}
+		ins.Arg, ins.Text = cut(rest)
+	case Reset:
+		if rest == "" {
+			return fail("missing label")
+		}
+		ins.Arg, ins.Text = cut(rest)
+	case Merge:
+		if o, r := cut(rest); o == "-C" || o == "-c" {
+			ins.Option = o
+			ins.Commit, rest = cut(r)
+			if ins.Commit == "" {
+				return fail("missing commit after " + o)
+			}
+		}
+		// The labels run up to an optional "# oneline" comment.
+		if i := strings.Index(rest, "#"); i >= 0 {
+			ins.Arg, ins.Text = strings.TrimSpace(rest[:i]), rest[i:]
+		} else {
+			ins.Arg = rest
+		}
+		if ins.Arg == "" {
+			return fail("missing label")
+		}
+	default: // commands taking a commit
+		if cmd == Fixup {
+			if o, r := cut(rest); o == "-C" || o == "-c" {
+				ins.Option, rest = o, r
+			}
+		}
+		ins.Commit, ins.Text = cut(rest)
+		if ins.Commit == "" {
+			return fail("missing commit")
+		}
+	}
+	ins.raw, ins.rawKey = line, ins.format()
+	return ins, nil
+}
+
+func argName(c Command) string {
+	if c == UpdateRef {
+		return "ref"
+	}
+	return "label"
+}
+
+// cut splits s at its first run of whitespace.
+func cut(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, " \t")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+// Write writes the list in todo format, one instruction per line.
+func Write(w io.Writer, list []Instruction) error {
+	bw := bufio.NewWriter(w)
+	for _, ins := range list {
+		if _, err := bw.WriteString(ins.String() + "\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Format returns the list in todo format.
+func Format(list []Instruction) string {
+	var b strings.Builder
+	_ = Write(&b, list)
+	return b.String()
+}
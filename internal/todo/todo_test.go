@@ -0,0 +1,145 @@
+package todo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		commentChar string
+		in          string
+	}{
+		{
+			name: "full commands",
+			in: "pick 1a2b3c4 First\n" +
+				"reword 2b3c4d5 Second\n" +
+				"edit 3c4d5e6 Third\n" +
+				"squash 4d5e6f7 Fourth\n" +
+				"fixup 5e6f7a8 Fifth\n" +
+				"drop 6f7a8b9 Sixth\n" +
+				"exec make test\n",
+		},
+		{
+			name: "abbreviated commands",
+			in:   "p 1a2b3c4 First\nr 2b3c4d5 Second\ne 3c4d5e6 Third\ns 4d5e6f7 Fourth\nf 5e6f7a8 Fifth\nd 6f7a8b9 Sixth\nx go vet ./...\nb\n",
+		},
+		{
+			name: "fixup options",
+			in:   "pick 1a2b3c4 First\nfixup -C 2b3c4d5 amend! First\nfixup -c 3c4d5e6 amend! First\nf -C 4d5e6f7 Fourth\n",
+		},
+		{
+			name: "merges",
+			in: "label onto\n" +
+				"reset onto\n" +
+				"pick 1a2b3c4 Feature\n" +
+				"label feature\n" +
+				"reset onto # Base\n" +
+				"merge -C 2b3c4d5 feature # Merge branch 'feature'\n" +
+				"merge -c 3c4d5e6 feature\n" +
+				"merge feature other\n",
+		},
+		{
+			name: "update-ref, break and noop",
+			in:   "pick 1a2b3c4 First\nupdate-ref refs/heads/stack\nbreak\nnoop\n",
+		},
+		{
+			name: "blank and comment lines",
+			in:   "pick 1a2b3c4 First\n\n# Rebase 0a1b2c3..1a2b3c4 onto 0a1b2c3 (1 command)\n#\n  # indented\n",
+		},
+		{
+			name:        "other comment character",
+			commentChar: ";",
+			in:          "; Commands:\npick 1a2b3c4 First\n;\n",
+		},
+		{
+			name: "spacing kept",
+			in:   "pick  1a2b3c4   Two  spaces\n\tpick 2b3c4d5 Tab\nexec   echo  spaced\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := Parse(strings.NewReader(tt.in), tt.commentChar)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := Format(list); got != tt.in {
+				t.Errorf("Format(Parse(in)) =\n%s\nwant\n%s", got, tt.in)
+			}
+		})
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want Instruction
+	}{
+		{"p 1a2b3c4 First commit", Instruction{Command: Pick, Abbrev: true, Commit: "1a2b3c4", Text: "First commit"}},
+		{"fixup -C 1a2b3c4 amend! First", Instruction{Command: Fixup, Option: "-C", Commit: "1a2b3c4", Text: "amend! First"}},
+		{"fixup -c 1a2b3c4", Instruction{Command: Fixup, Option: "-c", Commit: "1a2b3c4"}},
+		{"merge -C 1a2b3c4 feature # Merge branch 'feature'", Instruction{Command: Merge, Option: "-C", Commit: "1a2b3c4", Arg: "feature", Text: "# Merge branch 'feature'"}},
+		{"m a b", Instruction{Command: Merge, Abbrev: true, Arg: "a b"}},
+		{"reset onto # Base", Instruction{Command: Reset, Arg: "onto", Text: "# Base"}},
+		{"u refs/heads/stack", Instruction{Command: UpdateRef, Abbrev: true, Arg: "refs/heads/stack"}},
+		{"exec make -j4 test", Instruction{Command: Exec, Arg: "make -j4 test"}},
+		{"break", Instruction{Command: Break}},
+		{"noop", Instruction{Command: Noop}},
+		{"", Instruction{Command: Comment}},
+		{"# comment", Instruction{Command: Comment, Text: "# comment"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseLine(tt.line, "#")
+		if err != nil {
+			t.Errorf("ParseLine(%q): %v", tt.line, err)
+			continue
+		}
+		got.raw, got.rawKey = "", ""
+		if got != tt.want {
+			t.Errorf("ParseLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestStringAfterEdit(t *testing.T) {
+	ins, err := ParseLine("p  1a2b3c4  First", "#")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ins.Command = Reword
+	if got, want := ins.String(), "r 1a2b3c4 First"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		commentChar string
+		in          string
+		line        int
+	}{
+		{"unknown command", "", "pick 1a2b3c4 First\n\nfrobnicate 2b3c4d5\n", 3},
+		{"missing commit", "", "# header\npick\n", 2},
+		{"fixup option without commit", "", "fixup -C\n", 1},
+		{"merge without label", "", "pick 1a2b3c4 First\nmerge -C 2b3c4d5\n", 2},
+		{"break with arguments", "", "break now\n", 1},
+		{"exec without command", "", "pick 1a2b3c4 First\nexec\n", 2},
+		{"update-ref without ref", "", "update-ref\n", 1},
+		{"hash is no comment for another comment character", ";", "; ok\n# not a comment\n", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.in), tt.commentChar)
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Parse error = %v, want a *ParseError", err)
+			}
+			if pe.Line != tt.line {
+				t.Errorf("ParseError.Line = %d, want %d (%v)", pe.Line, tt.line, err)
+			}
+		})
+	}
+}
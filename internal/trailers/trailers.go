@@ -0,0 +1,173 @@
+// Package trailers parses the trailers of commit messages (the final
+// "Key: value" lines such as Signed-off-by) and merges the trailers of
+// commits that are squashed together.
+package trailers
+
+import (
+	"strings"
+)
+
+// Trailer is one "Key: value" line of a message's trailer block.
+type Trailer struct {
+	Key   string
+	Value string
+	// raw is the trailer as it was written, folded lines included, so it
+	// is joined back unchanged.
+	raw string
+}
+
+func (t Trailer) String() string {
+	if t.raw != "" {
+		return t.raw
+	}
+	return t.Key + ": " + t.Value
+}
+
+// Split separates msg into its text and its trailers. The trailers are the
+// last paragraph when every line of it is a "Key: value" line or indented
+// to continue the one before; a message of a single paragraph has none.
+func Split(msg string) (text string, trailers []Trailer) {
+	msg = strings.TrimRight(msg, "\n")
+	i := strings.LastIndex(msg, "\n\n")
+	if i < 0 {
+		return msg, nil
+	}
+	for _, l := range strings.Split(msg[i+2:], "\n") {
+		if l != "" && (l[0] == ' ' || l[0] == '\t') && len(trailers) > 0 {
+			// A folded line continues the previous value.
+			t := &trailers[len(trailers)-1]
+			t.Value = strings.TrimSpace(t.Value + " " + strings.TrimSpace(l))
+			t.raw += "\n" + l
+			continue
+		}
+		t, ok := parse(l)
+		if !ok {
+			return msg, nil
+		}
+		trailers = append(trailers, t)
+	}
+	return strings.TrimRight(msg[:i], "\n"), trailers
+}
+
+// parse parses a "Key: value" line. Keys are made of letters, digits and
+// dashes, and the colon is followed by whitespace or ends the line, as git
+// interpret-trailers expects; a URL such as "https://..." is no trailer.
+func parse(line string) (Trailer, bool) {
+	key, value, ok := strings.Cut(line, ":")
+	if !ok || key == "" || value != "" && value[0] != ' ' && value[0] != '\t' {
+		return Trailer{}, false
+	}
+	for _, r := range key {
+		if !(r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return Trailer{}, false
+		}
+	}
+	return Trailer{Key: key, Value: strings.TrimSpace(value), raw: line}, true
+}
+
+// Join appends trailers to text as its last paragraph.
+func Join(text string, trailers []Trailer) string {
+	if len(trailers) == 0 {
+		return text
+	}
+	lines := make([]string, len(trailers))
+	for i, t := range trailers {
+		lines[i] = t.String()
+	}
+	if text == "" {
+		return strings.Join(lines, "\n")
+	}
+	return text + "\n\n" + strings.Join(lines, "\n")
+}
+
+// Rule says which trailers with a given key survive a merge.
+type Rule string
+
+const (
+	// Unique keeps each value once, e.g. one Signed-off-by per person.
+	Unique Rule = "unique"
+	// All keeps every trailer, duplicates included.
+	All Rule = "all"
+	// First keeps only the first value, e.g. the Change-Id of the commit
+	// the others are squashed into.
+	First Rule = "first"
+	// Last keeps only the last value.
+	Last Rule = "last"
+	// Drop removes the trailers.
+	Drop Rule = "drop"
+)
+
+// ParseRule returns the rule named s, and false if there is none.
+func ParseRule(s string) (Rule, bool) {
+	switch r := Rule(strings.ToLower(strings.TrimSpace(s))); r {
+	case Unique, All, First, Last, Drop:
+		return r, true
+	}
+	return "", false
+}
+
+// Rules maps lowercased trailer keys to their rule; keys without one are
+// merged with Unique.
+type Rules map[string]Rule
+
+// DefaultRules keeps one Change-Id, the first, as Gerrit wants a single
+// one per commit, and de-duplicates everything else.
+func DefaultRules() Rules {
+	return Rules{"change-id": First}
+}
+
+func (r Rules) rule(key string) Rule {
+	if rule, ok := r[strings.ToLower(key)]; ok {
+		return rule
+	}
+	return Unique
+}
+
+// Merge combines the trailers of several messages, in order, applying the
+// rule of each key. Keys and values are compared ignoring case, and the
+// spelling of a key's first occurrence is kept; a trailer respelled that
+// way is written anew.
+func Merge(rules Rules, lists ...[]Trailer) []Trailer {
+	var all []Trailer
+	for _, l := range lists {
+		all = append(all, l...)
+	}
+	spelling := map[string]string{}
+	last := map[string]int{}
+	for i, t := range all {
+		k := strings.ToLower(t.Key)
+		if _, ok := spelling[k]; !ok {
+			spelling[k] = t.Key
+		}
+		last[k] = i
+	}
+	var res []Trailer
+	seen := map[string]bool{}
+	for i, t := range all {
+		k := strings.ToLower(t.Key)
+		if t.Key != spelling[k] {
+			t.Key, t.raw = spelling[k], ""
+		}
+		switch rules.rule(k) {
+		case Drop:
+			continue
+		case First:
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+		case Last:
+			if i != last[k] {
+				continue
+			}
+		case Unique:
+			kv := k + "\x00" + strings.ToLower(t.Value)
+			if seen[kv] {
+				continue
+			}
+			seen[kv] = true
+		}
+		res = append(res, t)
+	}
+	return res
+}
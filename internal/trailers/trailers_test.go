@@ -0,0 +1,167 @@
+package trailers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      string
+		text     string
+		trailers []Trailer
+	}{
+		{
+			name: "subject only",
+			msg:  "Fix the parser\n",
+			text: "Fix the parser",
+		},
+		{
+			name: "single paragraph of trailers is the subject",
+			msg:  "Signed-off-by: A <a@x>",
+			text: "Signed-off-by: A <a@x>",
+		},
+		{
+			name: "trailer block",
+			msg:  "Fix the parser\n\nIt dropped lines.\n\nSigned-off-by: A <a@x>\nChange-Id: I123\n",
+			text: "Fix the parser\n\nIt dropped lines.",
+			trailers: []Trailer{
+				{Key: "Signed-off-by", Value: "A <a@x>", raw: "Signed-off-by: A <a@x>"},
+				{Key: "Change-Id", Value: "I123", raw: "Change-Id: I123"},
+			},
+		},
+		{
+			name:     "folded value",
+			msg:      "Subject\n\nCo-authored-by: A\n  <a@x>",
+			text:     "Subject",
+			trailers: []Trailer{{Key: "Co-authored-by", Value: "A <a@x>", raw: "Co-authored-by: A\n  <a@x>"}},
+		},
+		{
+			name: "last paragraph isn't all trailers",
+			msg:  "Subject\n\nSee: the docs\nfor more.",
+			text: "Subject\n\nSee: the docs\nfor more.",
+		},
+		{
+			name: "URL",
+			msg:  "Fix the parser\n\nhttps://example.com/issues/1",
+			text: "Fix the parser\n\nhttps://example.com/issues/1",
+		},
+		{
+			name: "URL under a trailer",
+			msg:  "Fix the parser\n\nFixes: #1\nhttps://example.com/issues/1",
+			text: "Fix the parser\n\nFixes: #1\nhttps://example.com/issues/1",
+		},
+		{
+			name:     "colon ends the line",
+			msg:      "Subject\n\nSee-also:\n\tthe docs",
+			text:     "Subject",
+			trailers: []Trailer{{Key: "See-also", Value: "the docs", raw: "See-also:\n\tthe docs"}},
+		},
+		{
+			name: "key with a space",
+			msg:  "Subject\n\nNote this: not a trailer",
+			text: "Subject\n\nNote this: not a trailer",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, trailers := Split(tt.msg)
+			if text != tt.text {
+				t.Errorf("text = %q, want %q", text, tt.text)
+			}
+			if !reflect.DeepEqual(trailers, tt.trailers) {
+				t.Errorf("trailers = %v, want %v", trailers, tt.trailers)
+			}
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	ts := []Trailer{{Key: "Signed-off-by", Value: "A <a@x>"}, {Key: "Change-Id", Value: "I1"}}
+	if got, want := Join("Subject", ts), "Subject\n\nSigned-off-by: A <a@x>\nChange-Id: I1"; got != want {
+		t.Errorf("Join = %q, want %q", got, want)
+	}
+	if got, want := Join("", ts), "Signed-off-by: A <a@x>\nChange-Id: I1"; got != want {
+		t.Errorf("Join without text = %q, want %q", got, want)
+	}
+	if got, want := Join("Subject", nil), "Subject"; got != want {
+		t.Errorf("Join without trailers = %q, want %q", got, want)
+	}
+}
+
+func TestSplitJoinKeepsLines(t *testing.T) {
+	for _, msg := range []string{
+		"Subject\n\nSigned-off-by:   A <a@x>\nCo-authored-by: B\n  <b@x>",
+		"Subject\n\nhttps://example.com/issues/1",
+		"Subject\n\nKey:\tvalue",
+	} {
+		if got := Join(Split(msg)); got != msg {
+			t.Errorf("Join(Split(%q)) = %q", msg, got)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	for s, want := range map[string]Rule{"unique": Unique, " ALL ": All, "First": First, "last": Last, "drop": Drop} {
+		if got, ok := ParseRule(s); !ok || got != want {
+			t.Errorf("ParseRule(%q) = %q, %v, want %q", s, got, ok, want)
+		}
+	}
+	if _, ok := ParseRule("some"); ok {
+		t.Error(`ParseRule("some") succeeded`)
+	}
+}
+
+func TestMergeKeepsLines(t *testing.T) {
+	_, a := Split("A\n\nSigned-off-by:  A <a@x>\nCo-authored-by: C\n  <c@x>")
+	_, b := Split("B\n\nsigned-off-by: B <b@x>")
+	got := Join("", Merge(DefaultRules(), a, b))
+	// A trailer respelled after the first occurrence of its key is written
+	// anew; the others are kept as they were.
+	want := "Signed-off-by:  A <a@x>\nCo-authored-by: C\n  <c@x>\nSigned-off-by: B <b@x>"
+	if got != want {
+		t.Errorf("merged = %q, want %q", got, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a := []Trailer{{Key: "Signed-off-by", Value: "A <a@x>"}, {Key: "Change-Id", Value: "I1"}, {Key: "Reviewed-by", Value: "R <r@x>"}}
+	b := []Trailer{{Key: "signed-off-by", Value: "a <A@x>"}, {Key: "Change-Id", Value: "I2"}, {Key: "Signed-off-by", Value: "B <b@x>"}, {Key: "Reviewed-by", Value: "R <r@x>"}}
+	tests := []struct {
+		name  string
+		rules Rules
+		want  []Trailer
+	}{
+		{
+			name:  "defaults",
+			rules: DefaultRules(),
+			want:  []Trailer{{Key: "Signed-off-by", Value: "A <a@x>"}, {Key: "Change-Id", Value: "I1"}, {Key: "Reviewed-by", Value: "R <r@x>"}, {Key: "Signed-off-by", Value: "B <b@x>"}},
+		},
+		{
+			name:  "all",
+			rules: Rules{"reviewed-by": All, "change-id": Last},
+			want: []Trailer{
+				{Key: "Signed-off-by", Value: "A <a@x>"}, {Key: "Reviewed-by", Value: "R <r@x>"},
+				{Key: "Change-Id", Value: "I2"}, {Key: "Signed-off-by", Value: "B <b@x>"}, {Key: "Reviewed-by", Value: "R <r@x>"},
+			},
+		},
+		{
+			name:  "drop",
+			rules: Rules{"change-id": Drop, "reviewed-by": Drop},
+			want:  []Trailer{{Key: "Signed-off-by", Value: "A <a@x>"}, {Key: "Signed-off-by", Value: "B <b@x>"}},
+		},
+		{
+			name:  "first",
+			rules: Rules{"signed-off-by": First, "change-id": First},
+			want:  []Trailer{{Key: "Signed-off-by", Value: "A <a@x>"}, {Key: "Change-Id", Value: "I1"}, {Key: "Reviewed-by", Value: "R <r@x>"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Merge(tt.rules, a, b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
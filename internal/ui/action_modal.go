@@ -36,8 +36,11 @@ func (m *model) openActionModal() {
 	// Build items
 	opts := []list.Item{
 		actionOption{Act: pick},
+		actionOption{Act: reword},
 		actionOption{Act: squash},
 		actionOption{Act: fixup},
+		actionOption{Act: fixupUse},
+		actionOption{Act: fixupEdit},
 		actionOption{Act: edit},
 		actionOption{Act: drop},
 	}
@@ -70,17 +73,10 @@ func (m *model) openActionModal() {
 	idx := m.list.Index()
 	if idx >= 0 {
 		if ci, ok := m.list.Items()[idx].(commitItem); ok {
-			switch ci.Act {
-			case pick:
-				al.Select(0)
-			case squash:
-				al.Select(1)
-			case fixup:
-				al.Select(2)
-			case edit:
-				al.Select(3)
-			case drop:
-				al.Select(4)
+			for i, it := range opts {
+				if it.(actionOption).Act == ci.Act {
+					al.Select(i)
+				}
 			}
 		}
 	}
@@ -90,27 +86,17 @@ func (m *model) openActionModal() {
 }
 
 // applySelectedAction applies the selected action from the modal to the current commit.
-func (m *model) applySelectedAction() {
+func (m *model) applySelectedAction() tea.Cmd {
 	if !m.modalOpen {
-		return
+		return nil
 	}
-	idx := m.list.Index()
-	if idx < 0 {
-		return
+	if m.list.Index() < 0 {
+		return nil
 	}
-	sel := m.actList.Index()
-	switch sel {
-	case 0:
-		m.setAction(pick)
-	case 1:
-		m.setAction(squash)
-	case 2:
-		m.setAction(fixup)
-	case 3:
-		m.setAction(edit)
-	case 4:
-		m.setAction(drop)
+	if ao, ok := m.actList.SelectedItem().(actionOption); ok {
+		return m.setAction(ao.Act)
 	}
+	return nil
 }
 
 // simpleActionDelegate renders a plain list with "> " for the selected item
@@ -173,19 +159,7 @@ func (m model) renderActionModal(availW, availH int) string {
 	for i, it := range m.actList.Items() {
 		ao := it.(actionOption)
 		// Build colored label tag for the action
-		lblStyle := lipgloss.NewStyle().Padding(0, labelPad)
-		switch ao.Act {
-		case pick:
-			lblStyle = lblStyle.Background(theme.Green).Foreground(theme.Crust)
-		case squash:
-			lblStyle = lblStyle.Background(theme.Peach).Foreground(theme.Crust)
-		case fixup:
-			lblStyle = lblStyle.Background(theme.Yellow).Foreground(theme.Crust)
-		case edit:
-			lblStyle = lblStyle.Background(theme.Sky).Foreground(theme.Crust)
-		case drop:
-			lblStyle = lblStyle.Background(theme.Red).Foreground(theme.Crust)
-		}
+		lblStyle := actionStyle(ao.Act).Padding(0, labelPad)
 		// Capitalize action label text
 		lbl := string(ao.Act)
 		if len(lbl) > 0 {
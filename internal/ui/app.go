@@ -1,14 +1,21 @@
 package ui
 
 import (
+	"context"
+	"fmt"
 	"os"
 
 	key "github.com/charmbracelet/bubbles/v2/key"
 	list "github.com/charmbracelet/bubbles/v2/list"
+	spinner "github.com/charmbracelet/bubbles/v2/spinner"
+	textarea "github.com/charmbracelet/bubbles/v2/textarea"
+	textinput "github.com/charmbracelet/bubbles/v2/textinput"
+	viewport "github.com/charmbracelet/bubbles/v2/viewport"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/lipgloss/v2"
 
 	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
 	"github.com/fredrikmwold/rebasei-tui/internal/ui/theme"
 )
 
@@ -21,22 +28,172 @@ type model struct {
 	innerWidth  int
 	innerHeight int
 	ready       bool
+	// statusShown records whether the last layout left room for the status line
+	statusShown bool
 	// when true, quit the TUI and run rebase with the captured actions
 	doRebase bool
 	actions  []commands.CommitAction
+	// base is the commit the rebase runs onto; empty means the last N commits of HEAD
+	base commands.Base
+	// rebaseMerges runs the rebase with --rebase-merges
+	rebaseMerges bool
+	// updateRefs runs the rebase with --update-refs; branch is the branch
+	// being rebased, which needs no update-ref row
+	updateRefs bool
+	branch     string
+	// repo is the top of the working tree being rebased, shown in the title
+	repo string
+	// inPlace always runs git rebase, even for plans that could be rewritten
+	// in memory
+	inPlace bool
+	// lazy loading of older commits: stream is the rest of a range still to
+	// be read, window the size of a HEAD~n window (0 when a base was given)
+	// and pageSize how many commits a page adds; loading is set while a page
+	// is read, and rebaseWhenLoaded while the rest is read before a rebase
+	stream           *commands.CommitStream
+	window           int
+	pageSize         int
+	loading          bool
+	rebaseWhenLoaded bool
+
+	// background git work: the plan is loaded after the program starts
+	// (loadingPlan is set until then) and ctx is cancelled on quit, which
+	// kills any git still running
+	opts        Options
+	ctx         context.Context
+	cancel      context.CancelFunc
+	loadingPlan bool
+	spinner     spinner.Model
+	// rewriting is set while git changes the repository in the background,
+	// e.g. rewrites the commits in memory or restores a backup; busy while
+	// it reads something the next key needs, which quitting cancels
+	rewriting bool
+	busy      bool
+	// loadErr is a recognized failure that left nothing to plan; it is
+	// explained in place of the list
+	loadErr error
+	// autosquash is set while fixup!/squash!/amend! commits are arranged next
+	// to their targets; arranged records what each pass moved, to move it back
+	autosquash bool
+	arranged   [][]arrangedRow
+	// inProgress is the rebase git stopped in the middle of, if any; the list
+	// then shows its remaining plan followed by the instructions already run
+	inProgress *commands.RebaseState
+	// exitMessage is printed once the TUI has exited
+	exitMessage string
+
+	// conflict view shown while the rebase in progress has unmerged paths
+	conflictOpen bool
+	conflicts    []commands.Conflict
+	conflictIdx  int
+	conflictDiff viewport.Model
+
+	// stop view shown while the rebase in progress is stopped at an edit,
+	// a break or a failed exec
+	stopOpen        bool
+	stopHead        commands.Commit
+	stopStatusLines []string
+
+	// backup list for undoing an earlier rebase; undoOnly is set when the
+	// TUI was started just for that
+	undoOpen bool
+	undoOnly bool
+	backups  []commands.Backup
+	undoIdx  int
+
+	// dialog shown when local changes would stop git rebase from starting;
+	// autostash is set once the user chose to have git stash them, and
+	// backedUp once the backup was taken ahead of committing them as WIP
+	dirtyOpen bool
+	dirty     commands.WorkTree
+	autostash bool
+	backedUp  bool
+
+	// sign runs the rebase with -S, or else --no-gpg-sign; unsignedOK is
+	// set once the user was warned that signed commits lose their signature
+	sign       bool
+	unsignedOK bool
+
+	// background conflict prediction; predictor is nil when the plan can't
+	// be predicted, predictGen drops stale results, predictSig is the plan
+	// the last prediction was started for and predictCtx is cancelled, and
+	// its git killed, when the next one starts
+	predictor     *commands.Predictor
+	predictGen    int
+	predictSig    string
+	predictCtx    context.Context
+	predictCancel context.CancelFunc
+	// todoPath is set when running as git's sequence editor; the plan is
+	// written back to this file instead of starting a rebase.
+	todoPath string
 
 	// modal state for selecting an action via a small list
 	modalOpen bool
 	actList   list.Model
+
+	// commit message editor overlay used by reword
+	editorOpen bool
+	editor     textarea.Model
+	editorIdx  int
+	// composing is set while the editor holds the message of a squash
+	// group, composeKey being the group it was composed for
+	composing    bool
+	composeKey   string
+	composeCount int
+
+	// single-line prompt for exec commands
+	promptOpen bool
+	prompt     textinput.Model
+	promptMode promptMode
 }
 
-func initialModel() (model, error) {
-	commits, err := commands.ListCommits(20)
-	items := make([]list.Item, 0, max(0, len(commits)))
-	if err == nil {
-		for _, c := range commits {
-			items = append(items, commitItem{Commit: c, Act: pick})
+// Options configures a TUI session.
+type Options struct {
+	// Base names the upstream branch or commit to rebase onto. When empty the
+	// current branch's upstream is used, falling back to the last 20 commits.
+	Base string
+	// RebaseMerges keeps merge commits, rebuilding them with label/reset/merge
+	// instructions as "git rebase --rebase-merges" does.
+	RebaseMerges bool
+	// UpdateRefs starts with update-ref rows for the stacked branches in the
+	// plan, as "git rebase --update-refs" does.
+	UpdateRefs bool
+	// TodoFile is the git-rebase-todo path git passes to its sequence editor.
+	// When set, the TUI edits that file instead of starting its own rebase.
+	TodoFile string
+	// Undo only lists the backups taken before earlier rebases, to restore
+	// a branch from one of them.
+	Undo bool
+	// InPlace always runs git rebase in the working tree. By default plans
+	// that need no stop are rewritten in memory instead.
+	InPlace bool
+	// Count is how many commits are loaded up front, and then per page as
+	// the cursor nears the end of the list; 0 means defaultCount.
+	Count int
+	// Dir is the directory to work in instead of the current one, like
+	// "git -C"; it may be in a linked worktree or a submodule.
+	Dir string
+	// Sign starts with signing on, so the rewritten commits are signed even
+	// without commit.gpgSign.
+	Sign bool
+}
+
+// defaultCount is the number of commits loaded per page by default.
+const defaultCount = 20
+
+func initialModel(opts Options) (model, error) {
+	var items []list.Item
+	count := opts.Count
+	if count <= 0 {
+		count = defaultCount
+	}
+	if opts.TodoFile != "" {
+		// git is waiting on the todo; it is small and read right away.
+		plan, err := commands.ReadTodo(context.Background(), opts.TodoFile)
+		if err != nil {
+			return model{}, err
 		}
+		items = planItems(plan)
 	}
 
 	// Use a wrapped-item delegate to inject the action label while preserving
@@ -54,6 +211,12 @@ func initialModel() (model, error) {
 
 	l := list.New(items, delegate, 0, 0)
 	l.Title = "Interactive Rebase"
+	if opts.TodoFile != "" {
+		l.Title = "Edit Rebase Todo"
+	}
+	if opts.Undo {
+		l.Title = "Undo a Rebase"
+	}
 	l.Styles.Title = lipgloss.NewStyle().Bold(true).Foreground(theme.Blue)
 	// Use built-in list help line
 	l.SetShowHelp(true)
@@ -82,7 +245,8 @@ func initialModel() (model, error) {
 			upNav, downNav,
 			keys.MoveUp, keys.MoveDown,
 			keys.OpenAction,
-			keys.Pick, keys.Squash, keys.Fixup, keys.Edit, keys.Drop,
+			keys.Pick, keys.Reword, keys.Squash, keys.Fixup, keys.FixupUse, keys.FixupEdit, keys.Edit, keys.Drop,
+			keys.InsertExec, keys.ExecAll, keys.Break, keys.UpdateRefs, keys.Autosquash, keys.Sign, keys.Undo,
 			keys.Rebase, keys.Quit,
 		}
 	}
@@ -93,34 +257,226 @@ func initialModel() (model, error) {
 	km.CursorDown = key.Binding{}
 	l.KeyMap = km
 
-	status := ""
-	if err != nil {
-		status = "No commits found or not a Git repo. Open inside a repo to begin."
+	m := model{list: l, todoPath: opts.TodoFile, rebaseMerges: opts.RebaseMerges, inPlace: opts.InPlace, pageSize: count, opts: opts, conflictDiff: viewport.New()}
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	m.spinner = spinner.New(spinner.WithSpinner(spinner.Dot), spinner.WithStyle(lipgloss.NewStyle().Foreground(theme.Mauve)))
+	if opts.Undo {
+		// The backups are listed once the program runs.
+		m.undoOnly = true
+		m.busy = true
+		return m, nil
+	}
+	if opts.TodoFile != "" {
+		m.branch = commands.CurrentBranch(m.ctx)
+		// git already added update-ref rows if it was asked to.
+		for _, it := range m.list.Items() {
+			if ii, ok := it.(instructionItem); ok && ii.Ins.Command == todo.UpdateRef {
+				m.updateRefs = true
+			}
+		}
+		return m, nil
 	}
-	return model{list: l, status: status}, nil
+	// The plan is loaded in the background once the program runs.
+	m.loadingPlan = true
+	return m, nil
+}
+
+// planItems converts a plan (newest first) into list rows.
+func planItems(plan []commands.CommitAction) []list.Item {
+	items := make([]list.Item, 0, len(plan))
+	for _, ca := range plan {
+		if !ca.Instruction.Command.TakesCommit() {
+			items = append(items, instructionItem{Ins: ca.Instruction})
+			continue
+		}
+		items = append(items, commitItem{Commit: ca.Commit, Act: actionFor(ca.Instruction), ins: ca.Instruction})
+	}
+	return assignLanes(items)
 }
 
 // Using default list delegate for standard selection highlighting
 
-func (m model) Init() tea.Cmd { return nil }
+func (m model) Init() tea.Cmd {
+	if m.undoOnly {
+		return listBackups(m.ctx)
+	}
+	if !m.loadingPlan {
+		return nil
+	}
+	return tea.Batch(m.spinner.Tick, loadPlan(m.ctx, m.opts, m.pageSize))
+}
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	mm, ok := next.(model)
+	if !ok {
+		return next, cmd
+	}
+	// Re-layout when the status line appears or goes away.
+	if mm.ready && (mm.status != "") != mm.statusShown {
+		mm.layout()
+	}
+	// Re-predict conflicts whenever the plan changes.
+	if c := mm.schedulePrediction(); c != nil {
+		cmd = tea.Batch(cmd, c)
+	}
+	// Load older commits as the cursor nears the end of the list.
+	if c := mm.loadMore(); c != nil {
+		cmd = tea.Batch(cmd, c)
+	}
+	return mm, cmd
+}
+
+// layout sizes the list and overlays to the terminal, leaving room for the
+// status line when there is one.
+func (m *model) layout() {
+	// Compute inner size accounting for outer app border and optional status line inside it
+	innerW := m.width - 2 // border left+right
+	if innerW < 0 {
+		innerW = 0
+	}
+	innerH := m.height - 2 // border top+bottom
+	if m.status != "" {
+		innerH -= 1 // space for status line inside the border
+	}
+	if innerH < 0 {
+		innerH = 0
+	}
+	m.innerWidth, m.innerHeight = innerW, innerH
+
+	// Gate help & pagination visibility by size thresholds to avoid wrapping
+	const minHelpWidth = 52
+	const minPagWidth = 24
+	showHelp := innerH >= 6 && innerW >= minHelpWidth
+	showPagination := innerH >= 4 && innerW >= minPagWidth
+	m.list.SetShowHelp(showHelp)
+	m.list.SetShowPagination(showPagination)
+	m.list.SetShowStatusBar(false)
+	// Constrain help/footer to the available width so it won't exceed the box
+	m.list.Styles.HelpStyle = lipgloss.NewStyle().Foreground(theme.Surface2).MaxWidth(innerW)
+
+	// Compute how many chrome lines we have (title + optional pagination + optional help)
+	chrome := 0
+	if m.list.Title != "" {
+		chrome += 1
+	}
+	if showPagination {
+		chrome += 1
+	}
+	if showHelp {
+		chrome += 1
+	}
+	viewportH := max(0, innerH-chrome)
+	m.list.SetSize(innerW, viewportH)
+
+	// Calibrate viewport height so total rendered lines equals inner height.
+	for i := 0; i < 3; i++ {
+		lines := countLines(m.list.View())
+		delta := innerH - lines
+		if delta == 0 {
+			break
+		}
+		viewportH = max(0, viewportH+delta)
+		m.list.SetSize(innerW, viewportH)
+	}
+
+	if m.editorOpen {
+		m.editor.SetWidth(m.editorWidth())
+		m.editor.SetHeight(m.editorHeight())
+	}
+	if m.promptOpen {
+		m.prompt.SetWidth(m.editorWidth())
+	}
+	m.layoutConflicts()
+	m.statusShown = m.status != ""
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.(tea.WindowSizeMsg); !ok {
+		if m.editorOpen {
+			return m.updateMessageEditor(msg)
+		}
+		if m.promptOpen {
+			return m.updateCommandPrompt(msg)
+		}
+	}
 	switch msg := msg.(type) {
+	case rebaseStepMsg:
+		return m.afterRebaseStep(msg)
+	case stepStateMsg:
+		return m.applyStepState(msg)
+	case startMsg:
+		return m.afterStart(msg)
+	case inMemoryMsg:
+		return m.afterInMemory(msg)
+	case wipMsg:
+		return m.afterWIP(msg)
+	case composedMsg:
+		return m.applyComposed(msg)
+	case messageMsg:
+		return m.applyMessage(msg)
+	case backupsMsg:
+		return m.applyBackups(msg)
+	case restoredMsg:
+		return m.afterRestore(msg)
+	case pageMsg:
+		return m, m.appendPage(msg)
+	case planMsg:
+		return m, m.applyPlan(msg)
+	case conflictsMsg:
+		return m.applyConflicts(msg)
+	case conflictDiffMsg:
+		m.applyConflictDiff(msg)
+		return m, nil
+	case stopMsg:
+		m.applyStop(msg)
+		return m, nil
+	case spinner.TickMsg:
+		return m.updateSpinner(msg)
+	case predictTickMsg:
+		if msg.gen == m.predictGen && m.predictor != nil {
+			return m, m.predict(msg.gen)
+		}
+		return m, nil
+	case predictionMsg:
+		if msg.gen == m.predictGen && m.predictor != nil {
+			m.applyPrediction(msg)
+		}
+		return m, nil
+	case conflictsChangedMsg:
+		return m.afterConflictsChanged(msg)
 	case tea.KeyMsg:
+		if m.loadingPlan || m.busy {
+			// Keys wait for git; quitting cancels it.
+			if key.Matches(msg, keys.Quit) {
+				m.cancel()
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+		if m.rewriting {
+			// Quitting between the checkout and the ref update would leave
+			// the working tree on the new commits and HEAD on the old ones.
+			return m, nil
+		}
+		if m.undoOpen {
+			return m.updateUndo(msg)
+		}
+		if m.dirtyOpen {
+			return m.updateDirty(msg)
+		}
 		if m.modalOpen {
 			// Allow starting rebase directly from modal as well (Ctrl+Enter)
-			if key.Matches(msg, keys.Rebase) {
-				m.actions = m.collectActions()
-				m.doRebase = true
-				return m, tea.Quit
+			if key.Matches(msg, keys.Rebase) && m.inProgress == nil {
+				m.modalOpen = false
+				return m, m.rebase()
 			}
 			// Route keys to the action list when modal is open
 			// Handle confirm/cancel explicitly
 			switch msg.String() {
 			case "enter":
-				m.applySelectedAction()
 				m.modalOpen = false
-				return m, nil
+				return m, m.applySelectedAction()
 			case "esc", "q":
 				m.modalOpen = false
 				return m, nil
@@ -132,16 +488,87 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if key.Matches(msg, keys.Quit) {
 			return m, tea.Quit
 		}
+		if m.inProgress != nil {
+			switch {
+			case key.Matches(msg, keys.Continue):
+				return m, m.rebaseStep("--continue")
+			case key.Matches(msg, keys.Skip):
+				return m, m.rebaseStep("--skip")
+			case key.Matches(msg, keys.Abort):
+				return m, m.rebaseStep("--abort")
+			}
+			if m.conflictOpen {
+				return m.updateConflicts(msg)
+			}
+			if m.stopOpen {
+				return m.updateStopView(msg)
+			}
+			if key.Matches(msg, keys.StopView) {
+				if len(m.conflicts) > 0 {
+					return m, m.loadConflicts(false, nil)
+				} else if len(m.inProgress.Done) > 0 {
+					m.stopOpen = true
+					return m, m.loadStop(nil)
+				}
+				return m, nil
+			}
+		}
 		if key.Matches(msg, keys.Rebase) {
-			// capture current ordering and actions, quit TUI, and run rebase after p.Run returns
-			m.actions = m.collectActions()
-			m.doRebase = true
-			return m, tea.Quit
+			return m, m.rebase()
 		}
 		if key.Matches(msg, keys.OpenAction) {
-			m.openActionModal()
+			if ii, ok := m.list.SelectedItem().(instructionItem); ok {
+				if ii.Ins.Command == todo.Exec {
+					return m, m.openCommandPrompt(promptEditExec, ii.Ins.Arg)
+				}
+				return m, nil
+			}
+			if _, ok := m.list.SelectedItem().(commitItem); ok {
+				m.openActionModal()
+			}
+			return m, nil
+		}
+		if key.Matches(msg, keys.InsertExec) {
+			return m, m.openCommandPrompt(promptInsertExec, "")
+		}
+		if key.Matches(msg, keys.ExecAll) {
+			return m, m.openCommandPrompt(promptExecAll, "")
+		}
+		if key.Matches(msg, keys.UpdateRefs) {
+			m.setUpdateRefs(!m.updateRefs)
 			return m, nil
 		}
+		if key.Matches(msg, keys.Sign) {
+			if m.todoPath != "" || m.inProgress != nil {
+				m.status = lipgloss.NewStyle().Foreground(theme.Red).Render("git is already rebasing; signing is set when it starts (git rebase -S).")
+				return m, nil
+			}
+			m.sign = !m.sign
+			m.list.Title = m.planTitle()
+			m.status = "Rewritten commits will be left unsigned."
+			if m.sign {
+				m.status = "Rewritten commits will be signed (git rebase -S)."
+			}
+			return m, nil
+		}
+		if key.Matches(msg, keys.Undo) {
+			return m, m.openUndo()
+		}
+		if key.Matches(msg, keys.Autosquash) {
+			if m.todoPath != "" || m.rebaseMerges || m.inProgress != nil {
+				m.status = lipgloss.NewStyle().Foreground(theme.Red).Render("Autosquash works on the plain commit list; use git rebase --autosquash here.")
+				return m, nil
+			}
+			m.setAutosquash(!m.autosquash)
+			return m, nil
+		}
+		if key.Matches(msg, keys.Break) {
+			// Above the selection means the rebase stops right after that commit.
+			idx := m.insertIndex()
+			cmd := m.list.InsertItem(idx, instructionItem{Ins: todo.Instruction{Command: todo.Break}})
+			m.list.Select(idx)
+			return m, cmd
+		}
 		if key.Matches(msg, keys.MoveDown) {
 			m.moveSelected(1)
 			return m, nil
@@ -155,70 +582,40 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if key.Matches(msg, keys.Pick) {
 			return m, m.setAction(pick)
 		}
+		if key.Matches(msg, keys.Reword) {
+			return m, m.setAction(reword)
+		}
 		if key.Matches(msg, keys.Squash) {
 			return m, m.setAction(squash)
 		}
 		if key.Matches(msg, keys.Fixup) {
 			return m, m.setAction(fixup)
 		}
+		if key.Matches(msg, keys.FixupUse) {
+			return m, m.setAction(fixupUse)
+		}
+		if key.Matches(msg, keys.FixupEdit) {
+			return m, m.setAction(fixupEdit)
+		}
 		if key.Matches(msg, keys.Edit) {
 			return m, m.setAction(edit)
 		}
 		if key.Matches(msg, keys.Drop) {
+			if ii, ok := m.list.SelectedItem().(instructionItem); ok {
+				// The label/reset/merge rows of a --rebase-merges plan refer
+				// to each other and stay; other rows are removed outright.
+				if isStructural(ii) || ii.Ins.Command == todo.Merge {
+					m.status = lipgloss.NewStyle().Foreground(theme.Red).Render("Can't remove label, reset or merge rows; the branches of the plan depend on them.")
+					return m, nil
+				}
+				m.list.RemoveItem(m.list.Index())
+				return m, nil
+			}
 			return m, m.setAction(drop)
 		}
 	case tea.WindowSizeMsg:
 		m.width, m.height = msg.Width, msg.Height
-		// Compute inner size accounting for outer app border and optional status line inside it
-		innerW := msg.Width - 2 // border left+right
-		if innerW < 0 {
-			innerW = 0
-		}
-		innerH := msg.Height - 2 // border top+bottom
-		if m.status != "" {
-			innerH -= 1 // space for status line inside the border
-		}
-		if innerH < 0 {
-			innerH = 0
-		}
-		m.innerWidth, m.innerHeight = innerW, innerH
-
-		// Gate help & pagination visibility by size thresholds to avoid wrapping
-		const minHelpWidth = 52
-		const minPagWidth = 24
-		showHelp := innerH >= 6 && innerW >= minHelpWidth
-		showPagination := innerH >= 4 && innerW >= minPagWidth
-		m.list.SetShowHelp(showHelp)
-		m.list.SetShowPagination(showPagination)
-		m.list.SetShowStatusBar(false)
-		// Constrain help/footer to the available width so it won't exceed the box
-		m.list.Styles.HelpStyle = lipgloss.NewStyle().Foreground(theme.Surface2).MaxWidth(innerW)
-
-		// Compute how many chrome lines we have (title + optional pagination + optional help)
-		chrome := 0
-		if m.list.Title != "" {
-			chrome += 1
-		}
-		if showPagination {
-			chrome += 1
-		}
-		if showHelp {
-			chrome += 1
-		}
-		viewportH := max(0, innerH-chrome)
-		m.list.SetSize(innerW, viewportH)
-
-		// Calibrate viewport height so total rendered lines equals inner height.
-		for i := 0; i < 3; i++ {
-			lines := countLines(m.list.View())
-			delta := innerH - lines
-			if delta == 0 {
-				break
-			}
-			viewportH = max(0, viewportH+delta)
-			m.list.SetSize(innerW, viewportH)
-		}
-
+		m.layout()
 		m.ready = true
 	}
 
@@ -250,6 +647,18 @@ func (m *model) moveSelected(delta int) {
 	if newIdx < 0 || newIdx >= len(items) {
 		return
 	}
+	// label/reset rows delimit the branches of a --rebase-merges plan;
+	// rows may only be reordered within their branch.
+	if isStructural(items[idx]) || isStructural(items[newIdx]) {
+		return
+	}
+	// What a rebase in progress already ran stays where it is.
+	if _, ok := items[newIdx].(doneItem); ok {
+		return
+	}
+	if _, ok := items[idx].(doneItem); ok {
+		return
+	}
 	it := items[idx]
 	if delta > 0 {
 		copy(items[idx:], items[idx+1:newIdx+1])
@@ -271,8 +680,11 @@ func (m *model) setAction(a action) tea.Cmd {
 	if !ok {
 		return nil
 	}
-	// Disallow squash/fixup on the oldest (last) item to keep valid rebase order.
-	if idx == len(items)-1 && (a == squash || a == fixup) {
+	if a == reword {
+		return m.openMessageEditor()
+	}
+	// Disallow squash/fixup on the oldest commit to keep valid rebase order.
+	if a.melds() && !hasCommitBelow(items, idx) {
 		m.status = lipgloss.NewStyle().Foreground(theme.Red).Render("Can't squash/fixup the oldest commit. Move it above another or pick it.")
 		return nil
 	}
@@ -280,14 +692,83 @@ func (m *model) setAction(a action) tea.Cmd {
 	return m.list.SetItem(idx, ci)
 }
 
+// hasCommitBelow reports whether any commit row is older than the row at idx.
+// Below the rows of a rebase in progress, HEAD is where git stopped; like
+// git, any row it already ran counts.
+func hasCommitBelow(items []list.Item, idx int) bool {
+	for _, it := range items[idx+1:] {
+		switch it.(type) {
+		case commitItem, doneItem:
+			return true
+		}
+	}
+	return false
+}
+
+// rebase captures the plan and starts it: as git's sequence editor the TUI
+// quits and hands the plan back, otherwise it runs the rebase itself.
+func (m *model) rebase() tea.Cmd {
+	if m.loading {
+		m.status = "Still loading older commits; try again in a moment."
+		return nil
+	}
+	if m.rewriting {
+		return nil
+	}
+	if m.stream != nil {
+		// A rebase has to include every commit of the range.
+		m.rebaseWhenLoaded = true
+		m.status = "Loading the rest of the range before rebasing…"
+		return m.loadPage(-1)
+	}
+	// Squash messages are written here rather than in git's editor.
+	if cmd := m.composeSquash(); cmd != nil {
+		return cmd
+	}
+	return m.runPlan()
+}
+
+// runPlan hands the plan back to git as its sequence editor, or starts the
+// rebase once the squash messages are composed.
+func (m *model) runPlan() tea.Cmd {
+	m.actions = m.collectActions()
+	if m.todoPath != "" {
+		m.doRebase = true
+		return tea.Quit
+	}
+	if n := signedCommits(m.actions); n > 0 && !m.sign && !m.unsignedOK {
+		// Ask once before dropping signatures.
+		m.unsignedOK = true
+		m.status = lipgloss.NewStyle().Foreground(theme.Yellow).Render(
+			fmt.Sprintf("%d signed commits; rewritten ones lose their signature. S to sign, ctrl+r to go ahead.", n))
+		return nil
+	}
+	return m.startRebase()
+}
+
 func (m model) View() string {
 	content := m.list.View()
+	if m.loadingPlan {
+		content = m.renderLoading()
+	} else if m.loadErr != nil {
+		content = m.renderLoadError()
+	} else if m.conflictOpen {
+		content = m.renderConflicts()
+	} else if m.stopOpen {
+		content = m.renderStopView()
+	}
 	// Helper to build the app border style
 	appBoxStyle := func() lipgloss.Style {
 		return lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
 			BorderForeground(theme.Mauve).Foreground(theme.Text)
 	}
+	// The status line fills the box but must not wrap: the box's width
+	// includes its border.
+	statusStyle := func(boxWidth int) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(theme.Subtext0).Background(theme.Surface0).
+			Width(boxWidth - appBoxStyle().GetHorizontalFrameSize()).MaxHeight(1)
+	}
 	// Calibrate inner width so that the total rendered width (including border)
 	// exactly matches the terminal width. This compensates for terminals that
 	// treat box characters as ambiguous width.
@@ -306,9 +787,19 @@ func (m model) View() string {
 		}
 		return targetInner
 	}
-	if m.modalOpen {
-		// Build modal content
-		modal := m.renderActionModal(m.innerWidth, m.innerHeight)
+	var modal string
+	if m.undoOpen {
+		modal = m.renderUndo()
+	} else if m.dirtyOpen {
+		modal = m.renderDirty()
+	} else if m.modalOpen {
+		modal = m.renderActionModal(m.innerWidth, m.innerHeight)
+	} else if m.editorOpen {
+		modal = m.renderMessageEditor()
+	} else if m.promptOpen {
+		modal = m.renderCommandPrompt()
+	}
+	if modal != "" {
 		// Compose base content and modal using lipgloss compositor
 		// Ensure base layer spans the full inner width so centering works
 		baseContent := lipgloss.NewStyle().Width(m.innerWidth).Render(content)
@@ -330,9 +821,9 @@ func (m model) View() string {
 			appBox := appBoxStyle().Width(effectiveInner)
 			return appBox.Render(lipgloss.NewStyle().Width(effectiveInner).Render(rendered))
 		}
-		status := lipgloss.NewStyle().Foreground(theme.Subtext0).Background(theme.Surface0).Width(m.innerWidth).Render(m.status)
-		body := rendered + "\n" + status
 		effectiveInner := calibrateInner(m.innerWidth)
+		status := statusStyle(effectiveInner).Render(m.status)
+		body := rendered + "\n" + status
 		appBox := appBoxStyle().Width(effectiveInner)
 		return appBox.Render(lipgloss.NewStyle().Width(effectiveInner).Render(body))
 	}
@@ -344,7 +835,7 @@ func (m model) View() string {
 		return appBox.Render(body)
 	}
 	effectiveInner := calibrateInner(m.innerWidth)
-	status := lipgloss.NewStyle().Foreground(theme.Subtext0).Background(theme.Surface0).Width(effectiveInner).Render(m.status)
+	status := statusStyle(effectiveInner).Render(m.status)
 	body := lipgloss.NewStyle().Width(effectiveInner).Render(content) + "\n" + status
 	appBox := appBoxStyle().Width(effectiveInner)
 	return appBox.Render(body)
@@ -354,28 +845,71 @@ func (m model) collectActions() []commands.CommitAction {
 	items := m.list.Items()
 	cs := make([]commands.CommitAction, 0, len(items))
 	for _, it := range items {
-		ci := it.(commitItem)
-		cs = append(cs, commands.CommitAction{Commit: ci.Commit, Action: string(ci.Act)})
+		switch it := it.(type) {
+		case commitItem:
+			cs = append(cs, commands.CommitAction{Commit: it.Commit, Instruction: it.instruction(), Message: it.Message})
+		case instructionItem:
+			cs = append(cs, commands.CommitAction{Instruction: it.Ins})
+		}
 	}
 	return cs
 }
 
 // Run starts the TUI program.
-func Run() error {
-	m, err := initialModel()
-	if err != nil {
-		// Should not happen now, but keep as safety.
+func Run(opts Options) error {
+	if opts.Dir != "" {
+		if err := commands.OpenRepo(opts.Dir); err != nil {
+			return err
+		}
 	}
-	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithOutput(os.Stdout), tea.WithInput(os.Stdin))
-	if final, err := p.Run(); err != nil {
+	m, err := initialModel(opts)
+	if err != nil {
 		return err
-	} else if mm, ok := final.(model); ok && mm.doRebase {
-		// After exiting the TUI, run the rebase so the user regains full terminal control.
-		if err := commands.RunInteractiveRebase(mm.actions); err != nil {
+	}
+	popts := []tea.ProgramOption{tea.WithAltScreen()}
+	if opts.TodoFile != "" && !isTerminal(os.Stdin) {
+		// git may run its sequence editor without a terminal on stdin
+		popts = append(popts, tea.WithInputTTY())
+	} else {
+		popts = append(popts, tea.WithInput(os.Stdin))
+	}
+	if opts.TodoFile != "" && !isTerminal(os.Stdout) {
+		tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+		if err != nil {
 			return err
 		}
+		defer tty.Close()
+		popts = append(popts, tea.WithOutput(tty))
+	} else {
+		popts = append(popts, tea.WithOutput(os.Stdout))
+	}
+	p := tea.NewProgram(m, popts...)
+	final, err := p.Run()
+	// Stop any git still loading in the background.
+	m.cancel()
+	if mm, ok := final.(model); ok && mm.exitMessage != "" {
+		fmt.Println(mm.exitMessage)
+	}
+	if opts.TodoFile != "" {
+		// Write the edited plan back, or empty the todo so git aborts.
+		var plan []commands.CommitAction
+		if mm, ok := final.(model); ok && err == nil && mm.doRebase {
+			plan = mm.actions
+			// git has already recorded where the branch was; back it up
+			// like a rebase started from the TUI.
+			// Without one the rebase still runs; the edited plan isn't lost.
+			if s, serr := commands.InProgressRebase(context.Background()); serr == nil && s != nil && s.OrigHead != "" {
+				if _, berr := commands.CreateBackup(context.Background(), s.OrigHead, branchRef(s.HeadName), plan); berr != nil {
+					fmt.Fprintln(os.Stderr, "rebasei-tui: couldn't record a backup:", berr)
+				}
+			}
+		}
+		if werr := commands.WriteTodo(opts.TodoFile, plan); werr != nil {
+			return werr
+		}
+		return err
 	}
-	return nil
+	return err
 }
 
 // max returns the maximum of two ints.
@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+// commit returns a commit with a made-up hash derived from its subject and
+// the branches pointing at it.
+func commit(subject string, branches ...string) commands.Commit {
+	hash := fmt.Sprintf("%040x", subject)
+	hash = hash[len(hash)-40:]
+	return commands.Commit{Hash: hash, HashShort: hash[:7], Subject: subject, Branches: branches}
+}
+
+// pickRow returns the todo row picking c.
+func pickRow(c commands.Commit) commands.CommitAction {
+	return commands.CommitAction{Commit: c, Instruction: todo.Instruction{Command: todo.Pick, Commit: c.HashShort}}
+}
+
+// newModel returns the model of main once its plan, given newest first,
+// has loaded.
+func newModel(t *testing.T, commits ...commands.Commit) model {
+	t.Helper()
+	m, err := initialModel(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.applyPlan(planMsg{items: pickItems(commits), branch: "main"})
+	return m
+}
+
+// press sends keys to m one by one, ignoring the commands they return.
+func press(m model, keys ...string) model {
+	for _, k := range keys {
+		msg := tea.KeyPressMsg{Code: rune(k[0]), Text: k}
+		next, _ := m.Update(msg)
+		m = next.(model)
+	}
+	return m
+}
+
+// rowsOf describes the list newest first, one "action subject" per commit
+// row and the instruction itself for other rows.
+func rowsOf(m model) []string {
+	var res []string
+	for _, it := range m.list.Items() {
+		switch it := it.(type) {
+		case commitItem:
+			res = append(res, string(it.Act)+" "+it.Commit.Subject)
+		case instructionItem:
+			res = append(res, it.Ins.String())
+		case doneItem:
+			res = append(res, "done "+it.text())
+		}
+	}
+	return res
+}
+
+func TestDropKeepsMergeStructure(t *testing.T) {
+	m, err := initialModel(Options{RebaseMerges: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, f, c := commit("A"), commit("F"), commit("C")
+	ins := func(cmd todo.Command, arg string) commands.CommitAction {
+		return commands.CommitAction{Instruction: todo.Instruction{Command: cmd, Arg: arg}}
+	}
+	// Newest first, like the list.
+	plan := []commands.CommitAction{
+		pickRow(c),
+		ins(todo.Exec, "make"),
+		ins(todo.Merge, "feature"),
+		ins(todo.Reset, "branch-point"),
+		ins(todo.Label, "feature"),
+		pickRow(f),
+		ins(todo.Label, "branch-point"),
+		pickRow(a),
+		ins(todo.Reset, "onto"),
+		ins(todo.Label, "onto"),
+	}
+	m.applyPlan(planMsg{items: planItems(plan), branch: "main"})
+	want := rowsOf(m)
+	for i := 2; i < len(want); i++ {
+		if _, ok := m.list.Items()[i].(commitItem); ok {
+			continue
+		}
+		m.list.Select(i)
+		if m = press(m, "d"); !reflect.DeepEqual(rowsOf(m), want) {
+			t.Errorf("d on %q removed it", want[i])
+			return
+		}
+		if m.status == "" {
+			t.Errorf("d on %q gave no reason for keeping it", want[i])
+		}
+		m.status = ""
+	}
+	// Other rows go.
+	m.list.Select(1)
+	m = press(m, "d")
+	want = append(want[:1], want[2:]...)
+	if got := rowsOf(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows after dropping the exec =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestFixupOntoDoneCommit(t *testing.T) {
+	m := newModel(t, commit("B"), commit("A"))
+	m.list.Select(1)
+	if m = press(m, "f"); !reflect.DeepEqual(rowsOf(m), []string{"pick B", "pick A"}) {
+		t.Errorf("rows after f on the oldest commit = %q, want it refused", rowsOf(m))
+	}
+
+	// Resuming a rebase stopped at A, B may be melded into it.
+	a, b, c := commit("A"), commit("B"), commit("C")
+	m.loadInProgress(&commands.RebaseState{Todo: []commands.CommitAction{pickRow(c), pickRow(b)}, Done: []commands.CommitAction{pickRow(a)}})
+	m.list.Select(1)
+	m = press(m, "f")
+	want := []string{"pick C", "fixup B", "done pick " + a.HashShort + " A"}
+	if got := rowsOf(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows after f =\n%q\nwant\n%q", got, want)
+	}
+}
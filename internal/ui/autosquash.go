@@ -0,0 +1,191 @@
+package ui
+
+import (
+	"fmt"
+
+	list "github.com/charmbracelet/bubbles/v2/list"
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+	"github.com/fredrikmwold/rebasei-tui/internal/ui/theme"
+)
+
+// arrangedRow records a commit autosquash moved or gave an action, so that
+// turning autosquash off can put it back.
+type arrangedRow struct {
+	hash string
+	// after is the commit it followed before it was arranged
+	after string
+	// act is the action it had, and preset the one autosquash gave it
+	act, preset action
+}
+
+// setAutosquash moves fixup!/squash!/amend! commits next to their targets
+// and presets their actions, or moves them back to where they were. Rows
+// between commits (exec, break) stay with the commit they follow.
+func (m *model) setAutosquash(on bool) {
+	if !on {
+		m.autosquash = false
+		m.unarrange()
+		m.status = "Autosquash off; fixup!/squash!/amend! commits moved back."
+		return
+	}
+	m.autosquash = true
+	if moved := m.arrange(); moved == 0 {
+		m.status = "No fixup!, squash! or amend! commits to arrange."
+		return
+	}
+	if m.updateRefs {
+		// Ref updates move past the fixups and squashes of their commit.
+		m.setUpdateRefs(true)
+	}
+}
+
+// arrange moves the fixup!/squash!/amend! commits that are not arranged yet
+// next to their targets, and returns how many it arranged. It runs again
+// for each page of older commits, whose targets may be on later pages.
+func (m *model) arrange() int {
+	done := map[string]bool{}
+	for _, run := range m.arranged {
+		for _, a := range run {
+			done[a.hash] = true
+		}
+	}
+	items := m.list.Items()
+	// Group every commit with the rows above it up to the next commit;
+	// those run right after it. Rows below the oldest commit stay put.
+	var groups [][]list.Item
+	var plan []commands.CommitAction
+	byHash := map[string]int{}
+	var pending []list.Item
+	for _, it := range items {
+		ci, ok := it.(commitItem)
+		if !ok {
+			pending = append(pending, it)
+			continue
+		}
+		byHash[ci.Commit.Hash] = len(groups)
+		groups = append(groups, append(pending, it))
+		ca := commands.CommitAction{Commit: ci.Commit, Instruction: ci.instruction()}
+		if done[ci.Commit.Hash] {
+			// Autosquash leaves dropped commits where they are, which is
+			// where the user may have moved an arranged one since.
+			ca.Instruction.Command = todo.Drop
+		}
+		plan = append(plan, ca)
+		pending = nil
+	}
+	arranged, moved := commands.Autosquash(plan)
+	if moved == 0 {
+		return 0
+	}
+	// Build the result oldest first, and remember where each commit that
+	// got an action or moved as a fixup or squash came from.
+	chron := make([]list.Item, 0, len(items))
+	var run []arrangedRow
+	for i := len(arranged) - 1; i >= 0; i-- {
+		ca := arranged[i]
+		j := byHash[ca.Commit.Hash]
+		g := groups[j]
+		last := len(g) - 1
+		ci := g[last].(commitItem)
+		var after, now string
+		if j+1 < len(plan) {
+			after = plan[j+1].Commit.Hash
+		}
+		if i+1 < len(arranged) {
+			now = arranged[i+1].Commit.Hash
+		}
+		was := plan[j].Instruction
+		if c := ca.Instruction.Command; c != was.Command || ca.Instruction.Option != was.Option ||
+			after != now && (c == todo.Fixup || c == todo.Squash) {
+			act := actionFor(ca.Instruction)
+			run = append(run, arrangedRow{hash: ca.Commit.Hash, after: after, act: ci.Act, preset: act})
+			ci.Act = act
+		}
+		chron = append(chron, ci)
+		for k := last - 1; k >= 0; k-- {
+			chron = append(chron, g[k])
+		}
+	}
+	res := make([]list.Item, 0, len(items))
+	for i := len(chron) - 1; i >= 0; i-- {
+		res = append(res, chron[i])
+	}
+	res = append(res, pending...)
+	m.arranged = append(m.arranged, run)
+	idx := m.list.Index()
+	m.list.SetItems(res)
+	m.list.Select(idx)
+	m.status = lipgloss.NewStyle().Foreground(theme.Green).
+		Render(fmt.Sprintf("Autosquash moved %d commit(s) next to their targets; press a to undo.", moved))
+	return moved
+}
+
+// unarrange moves the commits autosquash arranged back above the commits
+// they followed, with the rows that run after them. Their actions go back
+// too unless they were changed since; everything else stays as it is.
+func (m *model) unarrange() {
+	if m.arranged == nil {
+		return
+	}
+	items := append([]list.Item(nil), m.list.Items()...)
+	// Undo the latest arrangement first. Within one, go oldest first, so the
+	// commit a row goes back above is already back.
+	for r := len(m.arranged) - 1; r >= 0; r-- {
+		for _, a := range m.arranged[r] {
+			items = unarrangeRow(items, a)
+		}
+	}
+	m.arranged = nil
+	idx := m.list.Index()
+	m.list.SetItems(items)
+	m.list.Select(min(idx, len(items)-1))
+	if m.updateRefs {
+		m.setUpdateRefs(true)
+	}
+}
+
+// unarrangeRow moves the commit a back above the commit it followed.
+func unarrangeRow(items []list.Item, a arrangedRow) []list.Item {
+	i := commitIndex(items, a.hash)
+	if i < 0 {
+		return items
+	}
+	start := groupStart(items, i)
+	g := append([]list.Item(nil), items[start:i+1]...)
+	if ci := g[len(g)-1].(commitItem); ci.Act == a.preset {
+		ci.Act = a.act
+		g[len(g)-1] = ci
+	}
+	items = append(items[:start], items[i+1:]...)
+	at := start
+	if j := commitIndex(items, a.after); j >= 0 {
+		at = groupStart(items, j)
+	}
+	items = append(items[:at], append(g, items[at:]...)...)
+	return items
+}
+
+// commitIndex returns the index of the row of the commit hash, or -1.
+func commitIndex(items []list.Item, hash string) int {
+	for i, it := range items {
+		if ci, ok := it.(commitItem); ok && ci.Commit.Hash == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+// groupStart returns where the rows that run after the commit at i begin:
+// the rows above it up to the next commit.
+func groupStart(items []list.Item, i int) int {
+	for i > 0 {
+		if _, ok := items[i-1].(commitItem); ok {
+			break
+		}
+		i--
+	}
+	return i
+}
@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+)
+
+func TestAutosquashOffKeepsChanges(t *testing.T) {
+	m := newModel(t, commit("C"), commit("squash! A"), commit("fixup! A"), commit("B"), commit("A"))
+	want := []string{"pick C", "pick B", "squash squash! A", "fixup fixup! A", "pick A"}
+	if got := rowsOf(m); !reflect.DeepEqual(got, want) {
+		t.Fatalf("arranged rows =\n%q\nwant\n%q", got, want)
+	}
+	m.list.Select(1)
+	m = press(m, "e")
+	m.list.Select(2)
+	m = press(m, "f")
+	m.list.Select(0)
+	m = press(m, "b")
+
+	m = press(m, "a")
+	want = []string{"break", "pick C", "fixup squash! A", "pick fixup! A", "edit B", "pick A"}
+	if got := rowsOf(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows with autosquash off =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestAutosquashArrangesLoadedPages(t *testing.T) {
+	m := newModel(t, commit("fixup! C"), commit("fixup! A"), commit("C"))
+	m.list.Select(1)
+	m = press(m, "s")
+	want := []string{"pick fixup! A", "squash fixup! C", "pick C"}
+	if got := rowsOf(m); !reflect.DeepEqual(got, want) {
+		t.Fatalf("first page =\n%q\nwant\n%q", got, want)
+	}
+
+	m.appendPage(pageMsg{commits: []commands.Commit{commit("fixup! B"), commit("B"), commit("A")}})
+	want = []string{"squash fixup! C", "pick C", "fixup fixup! B", "pick B", "fixup fixup! A", "pick A"}
+	if got := rowsOf(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows with the second page =\n%q\nwant\n%q", got, want)
+	}
+
+	m = press(m, "a")
+	want = []string{"squash fixup! C", "pick fixup! A", "pick C", "pick fixup! B", "pick B", "pick A"}
+	if got := rowsOf(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows with autosquash off =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"strings"
+
+	list "github.com/charmbracelet/bubbles/v2/list"
+	textinput "github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+	"github.com/fredrikmwold/rebasei-tui/internal/ui/theme"
+)
+
+// promptMode says what the single-line command prompt is used for.
+type promptMode int
+
+const (
+	promptInsertExec promptMode = iota // insert an exec row above the selection
+	promptEditExec                     // change the selected exec row
+	promptExecAll                      // insert an exec row after every commit
+)
+
+// openCommandPrompt opens the exec command prompt prefilled with value.
+func (m *model) openCommandPrompt(mode promptMode, value string) tea.Cmd {
+	ti := textinput.New()
+	ti.Prompt = "$ "
+	ti.Placeholder = "go test ./..."
+	ti.SetWidth(m.editorWidth())
+	ti.SetValue(value)
+	ti.CursorEnd()
+	m.prompt = ti
+	m.promptMode = mode
+	m.promptOpen = true
+	return m.prompt.Focus()
+}
+
+// updateCommandPrompt handles input while the command prompt is open.
+func (m model) updateCommandPrompt(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if km, ok := msg.(tea.KeyMsg); ok {
+		switch km.String() {
+		case "enter":
+			cmd := strings.TrimSpace(m.prompt.Value())
+			if cmd == "" {
+				return m, nil
+			}
+			m.promptOpen = false
+			exec := instructionItem{Ins: todo.Instruction{Command: todo.Exec, Arg: cmd}}
+			idx := max(0, m.list.Index())
+			switch m.promptMode {
+			case promptInsertExec:
+				idx = m.insertIndex()
+				// Rows are newest first, so inserting above the selection
+				// runs the command right after the selected commit.
+				c := m.list.InsertItem(idx, exec)
+				m.list.Select(idx)
+				return m, c
+			case promptEditExec:
+				return m, m.list.SetItem(idx, exec)
+			case promptExecAll:
+				m.insertExecAfterEach(exec)
+			}
+			return m, nil
+		case "esc":
+			m.promptOpen = false
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.prompt, cmd = m.prompt.Update(msg)
+	return m, cmd
+}
+
+// insertExecAfterEach inserts exec after every commit that is not melded into
+// by a following squash/fixup, like "git rebase --exec" does.
+func (m *model) insertExecAfterEach(exec instructionItem) {
+	items := m.list.Items()
+	res := make([]list.Item, 0, len(items)*2)
+	for i, it := range items {
+		ci, ok := it.(commitItem)
+		if ok && ci.Act != drop && !meldedIntoBy(items, i) {
+			// Skip when the same command already runs right after this commit.
+			if prev, ok := lastItem(res).(instructionItem); !ok || prev.Ins.String() != exec.Ins.String() {
+				res = append(res, exec)
+			}
+		}
+		res = append(res, it)
+	}
+	m.list.SetItems(res)
+}
+
+// meldedIntoBy reports whether the commit at idx is followed (chronologically,
+// i.e. directly above it) by a squash or fixup.
+func meldedIntoBy(items []list.Item, idx int) bool {
+	for i := idx - 1; i >= 0; i-- {
+		ci, ok := items[i].(commitItem)
+		if !ok {
+			return false
+		}
+		if ci.Act == drop {
+			continue
+		}
+		return ci.Act.melds()
+	}
+	return false
+}
+
+func lastItem(items []list.Item) list.Item {
+	if len(items) == 0 {
+		return nil
+	}
+	return items[len(items)-1]
+}
+
+// renderCommandPrompt renders the command prompt inside a bordered box.
+func (m model) renderCommandPrompt() string {
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		Padding(0, 1).
+		Foreground(theme.Text).
+		BorderForeground(theme.Mauve)
+	titleText := "Exec after the selected commit"
+	switch m.promptMode {
+	case promptEditExec:
+		titleText = "Edit exec command"
+	case promptExecAll:
+		titleText = "Exec after every commit"
+	}
+	title := lipgloss.NewStyle().Foreground(theme.Blue).Bold(true).Render(titleText)
+	hint := lipgloss.NewStyle().Foreground(theme.Subtext0).Render("enter confirm • esc cancel")
+	return box.Render(title + "\n\n" + m.prompt.View() + "\n\n" + hint)
+}
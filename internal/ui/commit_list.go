@@ -10,6 +10,7 @@ import (
 	"github.com/charmbracelet/lipgloss/v2"
 
 	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
 	"github.com/fredrikmwold/rebasei-tui/internal/ui/theme"
 )
 
@@ -17,6 +18,28 @@ import (
 type commitItem struct {
 	Commit commands.Commit
 	Act    action
+	// Message is the edited commit message for a reword
+	Message string
+	// ins is the todo instruction the row was read from, if any, so rows
+	// left unchanged are written back exactly as git wrote them.
+	ins todo.Instruction
+	// Lane is the indentation level of the row's branch in a --rebase-merges plan
+	Lane int
+	// Conflicts lists the paths the commit is predicted to conflict in
+	Conflicts []string
+	// composedFor is the squash group (see groupKey) Message was composed
+	// for, when the row is the newest of one
+	composedFor string
+}
+
+// instruction returns the todo instruction for the row's current action.
+func (c commitItem) instruction() todo.Instruction {
+	ins := c.ins
+	if ins.Commit == "" {
+		ins = todo.Instruction{Commit: c.Commit.Hash, Text: c.Commit.Subject}
+	}
+	ins.Command, ins.Option = c.Act.command()
+	return ins
 }
 
 func (c commitItem) Title() string { return c.Commit.Subject }
@@ -28,10 +51,92 @@ func (c commitItem) Description() string {
 	hashLbl := lbl(theme.Blue, "Hash:")
 	authorLbl := lbl(theme.Green, "Author:")
 	dateLbl := lbl(theme.Peach, "Date:")
-	return fmt.Sprintf("%s %s  %s %s  %s %s", hashLbl, c.Commit.HashShort, authorLbl, c.Commit.Author, dateLbl, c.Commit.Date)
+	desc := fmt.Sprintf("%s %s  %s %s  %s %s", hashLbl, c.Commit.HashShort, authorLbl, c.Commit.Author, dateLbl, c.Commit.Date)
+	if badge := signatureBadge(c.Commit); badge != "" {
+		desc += "  " + lbl(theme.Mauve, "Sig:") + " " + badge
+	}
+	if len(c.Conflicts) > 0 {
+		desc += "  " + lbl(theme.Red, "⚠ conflicts: "+strings.Join(c.Conflicts, ", "))
+	}
+	return desc
 }
 func (c commitItem) FilterValue() string { return c.Commit.Subject }
 
+// signatureBadge shows the state of the commit's signature and who made it.
+func signatureBadge(c commands.Commit) string {
+	var style lipgloss.Style
+	var text string
+	switch c.Signature {
+	case "G":
+		style, text = lipgloss.NewStyle().Foreground(theme.Green), "✓ good"
+	case "U":
+		style, text = lipgloss.NewStyle().Foreground(theme.Green), "✓ good (untrusted)"
+	case "B":
+		style, text = lipgloss.NewStyle().Foreground(theme.Red), "✗ bad"
+	case "R":
+		style, text = lipgloss.NewStyle().Foreground(theme.Red), "✗ revoked key"
+	case "X", "Y":
+		style, text = lipgloss.NewStyle().Foreground(theme.Yellow), "⚠ expired"
+	case "E":
+		style, text = lipgloss.NewStyle().Foreground(theme.Yellow), "? unknown key"
+	case "N":
+		return lipgloss.NewStyle().Foreground(theme.Surface2).Render("unsigned")
+	default:
+		return ""
+	}
+	if c.Signer != "" {
+		text += " " + c.Signer
+	}
+	return style.Render(text)
+}
+
+// signedCommits counts the signed commits the plan keeps.
+func signedCommits(plan []commands.CommitAction) int {
+	n := 0
+	for _, ca := range plan {
+		if ca.Commit.Signed() && ca.Instruction.Command != todo.Drop {
+			n++
+		}
+	}
+	return n
+}
+
+// instructionItem is a non-commit todo instruction: an exec or break row added
+// in the TUI, the label/reset/merge structure of a --rebase-merges plan, or
+// any other instruction carried through from a todo file git handed us.
+type instructionItem struct {
+	Ins  todo.Instruction
+	Lane int
+}
+
+func (t instructionItem) Title() string       { return t.Ins.String() }
+func (t instructionItem) Description() string { return "" }
+func (t instructionItem) FilterValue() string { return t.Ins.String() }
+
+// actionStyle returns the colored label style for an action badge.
+func actionStyle(a action) lipgloss.Style {
+	style := lipgloss.NewStyle()
+	switch a {
+	case pick:
+		style = style.Background(theme.Green).Foreground(theme.Crust)
+	case reword:
+		style = style.Background(theme.Blue).Foreground(theme.Crust)
+	case squash:
+		style = style.Background(theme.Peach).Foreground(theme.Crust)
+	case fixup:
+		style = style.Background(theme.Yellow).Foreground(theme.Crust)
+	case fixupUse:
+		style = style.Background(theme.Maroon).Foreground(theme.Crust)
+	case fixupEdit:
+		style = style.Background(theme.Flamingo).Foreground(theme.Crust)
+	case edit:
+		style = style.Background(theme.Sky).Foreground(theme.Crust)
+	case drop:
+		style = style.Background(theme.Red).Foreground(theme.Crust)
+	}
+	return style
+}
+
 // commitDelegate wraps DefaultDelegate and injects an action label before the title
 // while preserving default height/spacing and the built-in indicator.
 type commitDelegate struct{ list.DefaultDelegate }
@@ -42,9 +147,98 @@ type wrappedItem struct {
 }
 
 func (w wrappedItem) Title() string       { return w.title }
-func (w wrappedItem) Description() string { return w.base.Description() }
+func (w wrappedItem) Description() string { return lanePrefix(w.base.Lane) + w.base.Description() }
 func (w wrappedItem) FilterValue() string { return w.base.FilterValue() }
 
+type wrappedTodoLine struct{ title string }
+
+func (w wrappedTodoLine) Title() string       { return w.title }
+func (w wrappedTodoLine) Description() string { return "" }
+func (w wrappedTodoLine) FilterValue() string { return w.title }
+
+// isStructural reports whether it is a label or reset row, which delimit the
+// branches of a --rebase-merges plan.
+func isStructural(it list.Item) bool {
+	ii, ok := it.(instructionItem)
+	return ok && (ii.Ins.Command == todo.Label || ii.Ins.Command == todo.Reset)
+}
+
+// assignLanes indents the side branches of a --rebase-merges plan. Rows
+// between the first and the last reset (chronologically) belong to side
+// branches; the stretch after the last reset is the main line.
+func assignLanes(items []list.Item) []list.Item {
+	first, last := -1, -1
+	for i, it := range items {
+		if ii, ok := it.(instructionItem); ok && ii.Ins.Command == todo.Reset {
+			if last < 0 {
+				last = i
+			}
+			first = i
+		}
+	}
+	for i, it := range items {
+		lane := 0
+		if first >= 0 && i > last && i <= first {
+			lane = 1
+		}
+		switch it := it.(type) {
+		case commitItem:
+			it.Lane = lane
+			items[i] = it
+		case instructionItem:
+			it.Lane = lane
+			items[i] = it
+		}
+	}
+	return items
+}
+
+// lanePrefix renders the indentation guide for a row in the given lane.
+func lanePrefix(lane int) string {
+	if lane == 0 {
+		return ""
+	}
+	return lipgloss.NewStyle().Foreground(theme.Surface2).Render(strings.Repeat("│ ", lane))
+}
+
+// instructionBadge returns the badge text and style for a non-commit row.
+func instructionBadge(c todo.Command) (string, lipgloss.Style) {
+	style := lipgloss.NewStyle().Foreground(theme.Crust).Padding(0, 1)
+	switch c {
+	case todo.Exec:
+		return "Exec", style.Background(theme.Mauve)
+	case todo.Break:
+		return "Break", style.Background(theme.Surface2).Foreground(theme.Text)
+	case todo.Label:
+		return "Label", style.Background(theme.Blue)
+	case todo.Reset:
+		return "Reset", style.Background(theme.Peach)
+	case todo.Merge:
+		return "Merge", style.Background(theme.Green)
+	case todo.UpdateRef:
+		return "Update-ref", style.Background(theme.Teal)
+	}
+	return "", style
+}
+
+// instructionText returns the human-readable part of a non-commit row.
+func instructionText(ins todo.Instruction) string {
+	switch ins.Command {
+	case todo.Exec:
+		return "$ " + ins.Arg
+	case todo.Break:
+		return "stop here; resume with git rebase --continue"
+	case todo.UpdateRef:
+		return strings.TrimPrefix(ins.Arg, "refs/heads/")
+	case todo.Label, todo.Reset, todo.Merge:
+		if ins.Text != "" {
+			return ins.Arg + "  " + strings.TrimSpace(strings.TrimPrefix(ins.Text, "#"))
+		}
+		return ins.Arg
+	}
+	return ins.String()
+}
+
 func (d commitDelegate) Render(w io.Writer, m list.Model, index int, it list.Item) {
 	if ci, ok := it.(commitItem); ok {
 		// Build a colored action label tag with symmetric padding.
@@ -52,19 +246,7 @@ func (d commitDelegate) Render(w io.Writer, m list.Model, index int, it list.Ite
 		if len(lbl) > 0 {
 			lbl = strings.ToUpper(lbl[:1]) + lbl[1:]
 		}
-		style := lipgloss.NewStyle().Padding(0, 1)
-		switch ci.Act {
-		case pick:
-			style = style.Background(theme.Green).Foreground(theme.Crust)
-		case squash:
-			style = style.Background(theme.Peach).Foreground(theme.Crust)
-		case fixup:
-			style = style.Background(theme.Yellow).Foreground(theme.Crust)
-		case edit:
-			style = style.Background(theme.Sky).Foreground(theme.Crust)
-		case drop:
-			style = style.Background(theme.Red).Foreground(theme.Crust)
-		}
+		style := actionStyle(ci.Act).Padding(0, 1)
 		pre := style.Render(lbl) + " "
 		// Build optional tag badges if any
 		tagStr := ""
@@ -78,13 +260,56 @@ func (d commitDelegate) Render(w io.Writer, m list.Model, index int, it list.Ite
 			}
 			tagStr = " " + strings.Join(parts, "")
 		}
+		// Local branches as (branch) badges, like git log decorations
+		if len(ci.Commit.Branches) > 0 {
+			branchStyle := lipgloss.NewStyle().Foreground(theme.Sky)
+			parts := make([]string, 0, len(ci.Commit.Branches))
+			for _, b := range ci.Commit.Branches {
+				parts = append(parts, branchStyle.Render("("+b+")"))
+			}
+			tagStr += " " + strings.Join(parts, "")
+		}
 		subj := ci.Commit.Subject
+		if ci.Act == reword && ci.Message != "" {
+			subj, _, _ = strings.Cut(ci.Message, "\n")
+		}
 		if index == m.Index() {
 			subj = lipgloss.NewStyle().Foreground(theme.Mauve).Bold(true).Render(subj)
 		}
-		wi := wrappedItem{base: ci, title: pre + subj + tagStr}
+		wi := wrappedItem{base: ci, title: lanePrefix(ci.Lane) + pre + subj + tagStr}
 		d.DefaultDelegate.Render(w, m, index, wi)
 		return
 	}
+	if ii, ok := it.(instructionItem); ok {
+		var line string
+		if badge, style := instructionBadge(ii.Ins.Command); badge != "" {
+			text := lipgloss.NewStyle().Foreground(theme.Text).Render(instructionText(ii.Ins))
+			if index == m.Index() {
+				text = lipgloss.NewStyle().Foreground(theme.Mauve).Bold(true).Render(instructionText(ii.Ins))
+			}
+			line = style.Render(badge) + " " + text
+		} else {
+			line = lipgloss.NewStyle().Foreground(theme.Subtext0).Italic(true).Render(ii.Title())
+			if index == m.Index() {
+				line = lipgloss.NewStyle().Foreground(theme.Mauve).Italic(true).Render(ii.Title())
+			}
+		}
+		d.DefaultDelegate.Render(w, m, index, wrappedTodoLine{title: lanePrefix(ii.Lane) + line})
+		return
+	}
+	if di, ok := it.(doneItem); ok {
+		// Already run: dimmed, with the stop point called out.
+		style := lipgloss.NewStyle().Foreground(theme.Surface2)
+		if index == m.Index() {
+			style = style.Foreground(theme.Subtext0)
+		}
+		line := style.Render("✓ " + di.text())
+		if di.Current {
+			badge := lipgloss.NewStyle().Background(theme.Peach).Foreground(theme.Crust).Padding(0, 1).Render("Stopped")
+			line = badge + " " + lipgloss.NewStyle().Foreground(theme.Text).Render(di.text())
+		}
+		d.DefaultDelegate.Render(w, m, index, wrappedTodoLine{title: line})
+		return
+	}
 	d.DefaultDelegate.Render(w, m, index, it)
 }
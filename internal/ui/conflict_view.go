@@ -0,0 +1,257 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	key "github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/ui/theme"
+)
+
+// conflictsMsg carries the unmerged paths of the rebase in progress.
+// opening is set when the rebase state was just loaded, so the stop view
+// opens instead if there are none; changeErr is how changing them failed.
+type conflictsMsg struct {
+	conflicts []commands.Conflict
+	err       error
+	opening   bool
+	changeErr error
+}
+
+// loadConflicts reads the unmerged paths of the rebase in progress in the
+// background.
+func (m *model) loadConflicts(opening bool, changeErr error) tea.Cmd {
+	ctx := m.ctx
+	return func() tea.Msg {
+		cs, err := commands.Conflicts(ctx)
+		return conflictsMsg{conflicts: cs, err: err, opening: opening, changeErr: changeErr}
+	}
+}
+
+// applyConflicts opens the conflict view when there are unmerged paths, and
+// otherwise the stop view for a rebase that was just loaded.
+func (m model) applyConflicts(msg conflictsMsg) (tea.Model, tea.Cmd) {
+	if m.inProgress == nil {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.status = lipgloss.NewStyle().Foreground(theme.Red).Render("Couldn't list conflicts: " + msg.err.Error())
+		return m, nil
+	}
+	cs := msg.conflicts
+	m.conflicts = cs
+	m.conflictIdx = min(m.conflictIdx, max(0, len(cs)-1))
+	if len(cs) > 0 {
+		m.conflictOpen = true
+		m.status = fmt.Sprintf("%s: %d conflicted file(s)", m.stopStatus(), len(cs))
+	} else if m.conflictOpen {
+		m.status = lipgloss.NewStyle().Foreground(theme.Green).Render("All conflicts resolved; ctrl+r continues the rebase.")
+	} else if msg.opening && !m.inProgress.Apply && len(m.inProgress.Done) > 0 {
+		m.stopOpen = true
+		return m, tea.Batch(m.showConflictDiff(), m.loadStop(nil))
+	}
+	if msg.changeErr != nil {
+		m.status = lipgloss.NewStyle().Foreground(theme.Red).Render(msg.changeErr.Error())
+	}
+	return m, m.showConflictDiff()
+}
+
+// conflictDiffMsg carries the conflict hunks of path.
+type conflictDiffMsg struct {
+	path string
+	diff string
+	err  error
+}
+
+// showConflictDiff loads the conflict hunks of the selected path in the
+// background.
+func (m *model) showConflictDiff() tea.Cmd {
+	m.layoutConflicts()
+	if len(m.conflicts) == 0 {
+		m.conflictDiff.SetContent("")
+		return nil
+	}
+	ctx, path := m.ctx, m.conflicts[m.conflictIdx].Path
+	return func() tea.Msg {
+		diff, err := commands.ConflictDiff(ctx, path)
+		return conflictDiffMsg{path: path, diff: diff, err: err}
+	}
+}
+
+// applyConflictDiff shows the hunks loaded for the selected path; those of
+// a path no longer selected are dropped.
+func (m *model) applyConflictDiff(msg conflictDiffMsg) {
+	if len(m.conflicts) == 0 || m.conflicts[m.conflictIdx].Path != msg.path {
+		return
+	}
+	diff := msg.diff
+	if msg.err != nil {
+		diff = msg.err.Error()
+	}
+	m.conflictDiff.SetContent(colorConflictDiff(m.conflicts[m.conflictIdx], diff))
+	m.conflictDiff.GotoTop()
+}
+
+// conflictLayout splits the inner height between the file list and the diff.
+func (m model) conflictLayout() (fileRows, diffHeight int) {
+	fileRows = max(1, min(len(m.conflicts), m.innerHeight/3))
+	// title, separator and help line
+	diffHeight = max(0, m.innerHeight-fileRows-3)
+	return fileRows, diffHeight
+}
+
+// layoutConflicts sizes the diff viewport for the current terminal size.
+func (m *model) layoutConflicts() {
+	_, h := m.conflictLayout()
+	m.conflictDiff.SetWidth(m.innerWidth)
+	m.conflictDiff.SetHeight(h)
+}
+
+// updateConflicts handles keys while the conflict view is shown.
+func (m model) updateConflicts(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.StopView), msg.String() == "esc":
+		m.conflictOpen = false
+		m.status = m.stopStatus()
+		return m, nil
+	case msg.String() == "up":
+		if m.conflictIdx > 0 {
+			m.conflictIdx--
+			return m, m.showConflictDiff()
+		}
+		return m, nil
+	case msg.String() == "down":
+		if m.conflictIdx < len(m.conflicts)-1 {
+			m.conflictIdx++
+			return m, m.showConflictDiff()
+		}
+		return m, nil
+	}
+	if len(m.conflicts) == 0 {
+		return m, nil
+	}
+	ctx, c := m.ctx, m.conflicts[m.conflictIdx]
+	var resolve func() error
+	switch msg.String() {
+	case "o":
+		resolve = func() error { return commands.TakeSide(ctx, c, true) }
+	case "t":
+		resolve = func() error { return commands.TakeSide(ctx, c, false) }
+	case "a":
+		resolve = func() error { return commands.MarkResolved(ctx, c) }
+	case "m":
+		return m, tea.ExecProcess(commands.MergetoolCmd(m.repo, c.Path), func(err error) tea.Msg {
+			return conflictsChangedMsg{err: err}
+		})
+	default:
+		var cmd tea.Cmd
+		m.conflictDiff, cmd = m.conflictDiff.Update(msg)
+		return m, cmd
+	}
+	m.rewriting = true
+	return m, func() tea.Msg {
+		return conflictsChangedMsg{err: resolve(), resolved: true}
+	}
+}
+
+// conflictsChangedMsg reports that a path was resolved from the conflict
+// view, or that a merge tool finished with the conflicts.
+type conflictsChangedMsg struct {
+	err      error
+	resolved bool
+}
+
+// afterConflictsChanged reloads the conflicts once git or the merge tool
+// changed them.
+func (m model) afterConflictsChanged(msg conflictsChangedMsg) (tea.Model, tea.Cmd) {
+	err := msg.err
+	if msg.resolved {
+		m.rewriting = false
+	} else if err != nil {
+		err = fmt.Errorf("git mergetool: %w", err)
+	}
+	return m, m.loadConflicts(false, err)
+}
+
+// renderConflicts renders the conflict view: the unmerged paths, the hunks
+// of the selected one and the keys to resolve them.
+func (m model) renderConflicts() string {
+	w := m.innerWidth
+	fileRows, _ := m.conflictLayout()
+	title := lipgloss.NewStyle().Bold(true).Foreground(theme.Blue).Render(fmt.Sprintf("Conflicts (%d)", len(m.conflicts)))
+	lines := []string{" " + title}
+
+	if len(m.conflicts) == 0 {
+		lines = append(lines, lipgloss.NewStyle().Foreground(theme.Green).Render("  All conflicts resolved."))
+	}
+	// Scroll the file list so the selection stays visible.
+	first := max(0, min(m.conflictIdx-fileRows/2, len(m.conflicts)-fileRows))
+	for i := first; i < min(len(m.conflicts), first+fileRows); i++ {
+		c := m.conflicts[i]
+		badge := lipgloss.NewStyle().Background(theme.Red).Foreground(theme.Crust).Padding(0, 1).Render(c.Kind())
+		path := lipgloss.NewStyle().Foreground(theme.Text).Render(c.Path)
+		prefix := "  "
+		if i == m.conflictIdx {
+			prefix = lipgloss.NewStyle().Foreground(theme.Mauve).Render("│ ")
+			path = lipgloss.NewStyle().Foreground(theme.Mauve).Bold(true).Render(c.Path)
+		}
+		lines = append(lines, lipgloss.NewStyle().MaxWidth(w).Render(prefix+badge+" "+path))
+	}
+	for len(lines) < fileRows+1 {
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, lipgloss.NewStyle().Foreground(theme.Surface2).Render(strings.Repeat("─", w)))
+	diff := lipgloss.NewStyle().Width(w).Height(m.conflictDiff.Height()).MaxHeight(m.conflictDiff.Height()).Render(m.conflictDiff.View())
+	help := "↑/↓ file • o take ours (HEAD) • t take theirs (commit) • a mark resolved • m mergetool • pgup/pgdn scroll • tab plan • ctrl+r continue • ctrl+k skip • ctrl+a abort • q quit"
+	body := strings.Join(lines, "\n")
+	if m.conflictDiff.Height() > 0 {
+		body += "\n" + diff
+	}
+	return body + "\n" + lipgloss.NewStyle().Foreground(theme.Surface2).Render(truncateToWidth(help, w))
+}
+
+// colorConflictDiff colors the combined diff of an unmerged path, or
+// explains the conflict when there are no hunks to show.
+func colorConflictDiff(c commands.Conflict, diff string) string {
+	if strings.TrimSpace(diff) == "" || !strings.Contains(diff, "@@") {
+		return lipgloss.NewStyle().Foreground(theme.Subtext0).Render(
+			c.Path + " was " + c.Kind() + ".\nTake ours (o) or theirs (t) to keep that side's version; a side that deleted it removes it.")
+	}
+	header := lipgloss.NewStyle().Foreground(theme.Surface2)
+	hunk := lipgloss.NewStyle().Foreground(theme.Blue)
+	marker := lipgloss.NewStyle().Foreground(theme.Peach).Bold(true)
+	added := lipgloss.NewStyle().Foreground(theme.Green)
+	removed := lipgloss.NewStyle().Foreground(theme.Red)
+	text := lipgloss.NewStyle().Foreground(theme.Text)
+	lines := strings.Split(strings.TrimRight(diff, "\n"), "\n")
+	inHunks := false
+	for i, l := range lines {
+		// A combined diff has one prefix column per parent.
+		cols, body := "", l
+		if len(l) >= 2 {
+			cols, body = l[:2], l[2:]
+		}
+		switch {
+		case strings.HasPrefix(l, "@@"):
+			inHunks = true
+			lines[i] = hunk.Render(l)
+		case !inHunks:
+			lines[i] = header.Render(l)
+		case strings.HasPrefix(body, "<<<<<<<"), strings.HasPrefix(body, "======="),
+			strings.HasPrefix(body, ">>>>>>>"), strings.HasPrefix(body, "|||||||"):
+			lines[i] = marker.Render(l)
+		case strings.Contains(cols, "+"):
+			lines[i] = added.Render(l)
+		case strings.Contains(cols, "-"):
+			lines[i] = removed.Render(l)
+		default:
+			lines[i] = text.Render(l)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/ui/theme"
+)
+
+// updateDirty handles keys while the local changes dialog is open.
+func (m model) updateDirty(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "s":
+		m.dirtyOpen = false
+		m.autostash = true
+		return m, m.startRebase()
+	case "w":
+		m.dirtyOpen = false
+		m.rewriting = true
+		ctx, branch, actions, sign := m.ctx, m.backupBranch(), m.actions, m.sign
+		return m, func() tea.Msg {
+			// Back up the branch as it was, before the WIP commit.
+			if _, err := commands.CreateBackup(ctx, "HEAD", branch, actions); err != nil {
+				return wipMsg{backupErr: err}
+			}
+			wip, err := commands.CommitWIP(ctx, sign)
+			return wipMsg{wip: wip, err: err}
+		}
+	case "esc", "q":
+		m.dirtyOpen = false
+	}
+	return m, nil
+}
+
+// wipMsg reports the WIP commit made from the local changes, or how taking
+// the backup before it or making it failed.
+type wipMsg struct {
+	wip       commands.CommitAction
+	backupErr error
+	err       error
+}
+
+// afterWIP starts the rebase with the WIP commit picked on top.
+func (m model) afterWIP(msg wipMsg) (tea.Model, tea.Cmd) {
+	m.rewriting = false
+	switch {
+	case msg.backupErr != nil:
+		m.status = lipgloss.NewStyle().Foreground(theme.Red).Render("Couldn't record a backup: " + msg.backupErr.Error())
+		return m, nil
+	case msg.err != nil:
+		m.status = errorStatus("Couldn't commit the changes: ", msg.err)
+		return m, nil
+	}
+	// The backup is taken; the WIP commit is the newest one.
+	m.backedUp = true
+	wip := msg.wip
+	m.actions = append([]commands.CommitAction{wip}, m.actions...)
+	m.list.InsertItem(0, commitItem{Commit: wip.Commit, Act: pick, ins: wip.Instruction})
+	m.list.Select(m.list.Index() + 1)
+	return m, m.startRebase()
+}
+
+// renderDirty renders the local changes git rebase would refuse to start
+// with, grouped like git status, and what can be done about them.
+func (m model) renderDirty() string {
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		Padding(0, 1).
+		Foreground(theme.Text).
+		BorderForeground(theme.Mauve)
+	w := max(20, min(80, m.innerWidth-6))
+	title := lipgloss.NewStyle().Foreground(theme.Blue).Bold(true).Render("Uncommitted changes")
+	intro := lipgloss.NewStyle().Width(w).Foreground(theme.Subtext0).
+		Render("git rebase won't start with local changes to tracked files.")
+	hint := lipgloss.NewStyle().Foreground(theme.Subtext0).Render("s autostash • w commit as WIP • esc back to the plan")
+
+	groups := []struct {
+		name  string
+		lines []string
+	}{
+		{"Staged", m.dirty.Staged},
+		{"Unstaged", m.dirty.Unstaged},
+		{"Untracked", m.dirty.Untracked},
+	}
+	// Keep the box within the screen when there are many changes.
+	room := max(3, m.innerHeight-10)
+	dim := lipgloss.NewStyle().Foreground(theme.Subtext0)
+	var lines []string
+	for _, g := range groups {
+		if len(g.lines) == 0 {
+			continue
+		}
+		lines = append(lines, dim.Render(fmt.Sprintf("%s (%d)", g.name, len(g.lines))))
+		for i, l := range g.lines {
+			if len(lines) >= room-1 && i < len(g.lines)-1 {
+				lines = append(lines, dim.Render(fmt.Sprintf("  … %d more", len(g.lines)-i)))
+				break
+			}
+			lines = append(lines, lipgloss.NewStyle().MaxWidth(w).Render("  "+colorStatusLine(l)))
+		}
+	}
+	return box.Render(title + "\n" + intro + "\n\n" + strings.Join(lines, "\n") + "\n\n" + hint)
+}
@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"errors"
+
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/ui/theme"
+)
+
+// errorHelp says what a recognized git failure means and how to get past it.
+type errorHelp struct {
+	kind  error
+	title string
+	fix   string
+}
+
+// errorHelps are kept short enough for both to fit on the status line.
+var errorHelps = []errorHelp{
+	{commands.ErrGitNotFound,
+		"git isn't installed or isn't on PATH.",
+		"Install git, then check git --version."},
+	{commands.ErrNotRepo,
+		"Not inside a git repository.",
+		"Run it from a repository, or git init one."},
+	{commands.ErrNoWorkTree,
+		"This repository has no working tree to rebase in.",
+		"Run it from a checkout or a worktree of the repository."},
+	{commands.ErrUnbornBranch,
+		"This branch has no commits yet.",
+		"Make a first commit to have something to rebase."},
+	{commands.ErrDetachedHead,
+		"HEAD is detached; no branch will move.",
+		"git switch <branch> first to rebase a branch."},
+	{commands.ErrDirtyTree,
+		"Uncommitted changes are in the way.",
+		"Commit or git stash them, then try again."},
+	{commands.ErrRebaseInProgress,
+		"A rebase is already in progress.",
+		"Restart to resume it, or git rebase --continue/--abort."},
+	{commands.ErrLocked,
+		"A git .lock file exists; is another git running?",
+		"If not, delete the lock file and try again."},
+}
+
+// explain returns the help for err when it is a failure the UI knows.
+func explain(err error) (errorHelp, bool) {
+	for _, h := range errorHelps {
+		if errors.Is(err, h.kind) {
+			return h, true
+		}
+	}
+	return errorHelp{}, false
+}
+
+// errorStatus renders err for the status line: what went wrong and the fix
+// when the failure is known, otherwise prefix and git's message.
+func errorStatus(prefix string, err error) string {
+	if h, ok := explain(err); ok {
+		return lipgloss.NewStyle().Foreground(theme.Red).Render(h.title) + " " +
+			lipgloss.NewStyle().Foreground(theme.Subtext0).Render(h.fix)
+	}
+	return lipgloss.NewStyle().Foreground(theme.Red).Render(prefix + err.Error())
+}
+
+// renderLoadError fills the list area when the plan couldn't be loaded:
+// what went wrong, how to fix it, and git's own message.
+func (m model) renderLoadError() string {
+	h, _ := explain(m.loadErr)
+	w := max(20, min(80, m.innerWidth-4))
+	wrap := lipgloss.NewStyle().Width(w)
+	text := wrap.Foreground(theme.Red).Bold(true).Render(h.title) + "\n\n" +
+		wrap.Foreground(theme.Text).Render(h.fix) + "\n\n"
+	// Show what git said, when it said anything.
+	var ge *commands.GitError
+	if errors.As(m.loadErr, &ge) && ge.Stderr != "" {
+		text += wrap.Foreground(theme.Subtext0).Render(ge.Error()) + "\n\n"
+	}
+	text += lipgloss.NewStyle().Foreground(theme.Surface2).Render("q quit")
+	return lipgloss.Place(m.innerWidth, m.innerHeight, lipgloss.Center, lipgloss.Center, text)
+}
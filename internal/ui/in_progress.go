@@ -0,0 +1,356 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	key "github.com/charmbracelet/bubbles/v2/key"
+	list "github.com/charmbracelet/bubbles/v2/list"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+	"github.com/fredrikmwold/rebasei-tui/internal/ui/theme"
+)
+
+// doneItem is an instruction of the rebase in progress that git already ran.
+// Done rows are shown below the remaining plan and can't be changed.
+type doneItem struct {
+	Ins    todo.Instruction
+	Commit commands.Commit
+	// Current marks the instruction git stopped at.
+	Current bool
+}
+
+func (d doneItem) Title() string       { return d.Ins.String() }
+func (d doneItem) Description() string { return "" }
+func (d doneItem) FilterValue() string { return d.Ins.String() }
+
+// text returns the row's instruction with the commit's subject.
+func (d doneItem) text() string {
+	if d.Ins.Command.TakesCommit() && d.Commit.Subject != "" {
+		return string(d.Ins.Command) + " " + d.Commit.HashShort + " " + d.Commit.Subject
+	}
+	return d.Ins.String()
+}
+
+// rebaseStepMsg reports the end of a git rebase command, or of a shell the
+// user may have continued the rebase from (with an empty flag).
+type rebaseStepMsg struct {
+	flag   string
+	output string
+	err    error
+}
+
+// loadInProgress shows the rebase in progress: the remaining plan on top,
+// editable like a new one, and the instructions already run below it. The
+// conflicts, or else where git stopped, are read in the background.
+func (m *model) loadInProgress(s *commands.RebaseState) tea.Cmd {
+	m.inProgress = s
+	m.autosquash, m.arranged = false, nil
+	m.predictor = nil
+	items := planItems(s.Todo)
+	for i, ca := range s.Done {
+		items = append(items, doneItem{Ins: ca.Instruction, Commit: ca.Commit, Current: i == 0})
+	}
+	m.list.SetItems(items)
+	m.list.Select(0)
+	m.list.Title = "Rebase in progress: " + m.repoLabel(s.Branch())
+	if s.Onto != "" {
+		m.list.Title += " onto " + shortHash(s.Onto)
+	}
+	m.list.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.MoveUp, keys.MoveDown, keys.OpenAction, keys.StopView, keys.Continue, keys.Skip, keys.Abort, keys.Quit}
+	}
+	m.list.AdditionalFullHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			keys.MoveUp, keys.MoveDown,
+			keys.OpenAction,
+			keys.Pick, keys.Reword, keys.Squash, keys.Fixup, keys.FixupUse, keys.FixupEdit, keys.Edit, keys.Drop,
+			keys.InsertExec, keys.Break, keys.UpdateRefs,
+			keys.StopView, keys.Continue, keys.Skip, keys.Abort, keys.Quit,
+		}
+	}
+	m.status = m.stopStatus()
+	m.conflictOpen, m.stopOpen = false, false
+	m.conflicts = nil
+	return m.loadConflicts(true, nil)
+}
+
+// stopStatus describes where the rebase in progress stopped.
+func (m model) stopStatus() string {
+	s := m.inProgress
+	if s.Apply {
+		return "A rebase (apply backend) is in progress; its todo can't be edited."
+	}
+	if len(s.Done) == 0 {
+		return "A rebase is in progress."
+	}
+	return "Stopped at " + doneItem{Ins: s.Done[0].Instruction, Commit: s.Done[0].Commit}.text()
+}
+
+// rebaseStep writes the edited remaining plan back to git's todo, unless
+// aborting, and runs git rebase with flag attached to the terminal.
+func (m *model) rebaseStep(flag string) tea.Cmd {
+	if path := m.inProgress.TodoPath(); path != "" && flag != "--abort" {
+		if err := commands.WriteTodo(path, m.collectActions()); err != nil {
+			m.status = lipgloss.NewStyle().Foreground(theme.Red).Render("Couldn't write the todo: " + err.Error())
+			return nil
+		}
+	}
+	return execRebase(commands.RebaseStep(flag), flag, nil)
+}
+
+// startRebase runs the planned rebase. A plan that needs no stop is
+// rewritten in memory and the TUI quits; otherwise git rebase runs attached
+// to the terminal and the TUI comes back to show where git stopped, if it did.
+func (m *model) startRebase() tea.Cmd {
+	return m.prepareRebase(!m.inPlace, !m.backedUp, nil)
+}
+
+// startMsg reports what was found before the rebase could start: local
+// changes git rebase would refuse to start with, or a failed backup.
+// inMemoryErr is why an in-memory rebase fell back to git rebase, if it did.
+type startMsg struct {
+	inMemory    bool
+	dirty       commands.WorkTree
+	backupErr   error
+	inMemoryErr error
+}
+
+// prepareRebase decides in the background whether the plan can be rewritten
+// in memory, when that is allowed, checks the working tree unless the
+// rebase runs in memory or autostashes, and records where the branch is so
+// the rebase can be undone.
+func (m *model) prepareRebase(inMemory, backup bool, inMemoryErr error) tea.Cmd {
+	ctx, opts, actions, branch := m.ctx, m.rebaseOptions(), m.actions, m.backupBranch()
+	autostash := m.autostash
+	m.rewriting = true
+	return func() tea.Msg {
+		inMemory := inMemory && commands.InMemory(ctx, opts, actions)
+		msg := startMsg{inMemory: inMemory, inMemoryErr: inMemoryErr}
+		if !inMemory && !autostash {
+			// git rebase refuses to start with local changes; ask first.
+			// When git status fails, let git rebase report what is wrong.
+			if wt, err := commands.WorkTreeStatus(ctx); err == nil && wt.Dirty() {
+				msg.dirty = wt
+				return msg
+			}
+		}
+		if backup {
+			_, msg.backupErr = commands.CreateBackup(ctx, "HEAD", branch, actions)
+		}
+		return msg
+	}
+}
+
+// afterStart runs the rebase prepareRebase cleared, or opens the dialog
+// about the local changes in its way.
+func (m model) afterStart(msg startMsg) (tea.Model, tea.Cmd) {
+	m.rewriting = false
+	switch {
+	case msg.dirty.Dirty():
+		m.dirty = msg.dirty
+		m.dirtyOpen = true
+		return m, nil
+	case msg.backupErr != nil:
+		m.status = lipgloss.NewStyle().Foreground(theme.Red).Render("Couldn't record a backup: " + msg.backupErr.Error())
+		return m, nil
+	case msg.inMemoryErr != nil && !errors.Is(msg.inMemoryErr, commands.ErrNeedsStop):
+		// Something other than the tracked changes is in the way.
+		m.status = errorStatus("", msg.inMemoryErr)
+		return m, nil
+	}
+	// The backup taken before a WIP commit has served its purpose.
+	m.backedUp = false
+	if msg.inMemory {
+		m.status = "Rewriting the commits in memory…"
+		m.rewriting = true
+		opts, actions := m.rebaseOptions(), m.actions
+		return m, func() tea.Msg {
+			n, err := commands.RebaseInMemory(opts, actions)
+			return inMemoryMsg{rewritten: n, err: err}
+		}
+	}
+	return m, m.interactiveRebase(m.rebaseOptions())
+}
+
+// backupBranch returns the full name of the branch being rebased, which
+// its backup records, or "" for a detached HEAD.
+func (m model) backupBranch() string {
+	if m.branch == "" {
+		return ""
+	}
+	return "refs/heads/" + m.branch
+}
+
+// inMemoryMsg reports the end of an in-memory rebase.
+type inMemoryMsg struct {
+	rewritten int
+	err       error
+}
+
+// afterInMemory quits once the in-memory rebase is done, or falls back to
+// git rebase when the plan turned out to need a stop.
+func (m model) afterInMemory(msg inMemoryMsg) (tea.Model, tea.Cmd) {
+	m.rewriting = false
+	switch {
+	case msg.err == nil:
+		m.exitMessage = fmt.Sprintf("Rebased in memory: %d commits rewritten.", msg.rewritten)
+		if m.branch != "" {
+			m.exitMessage = fmt.Sprintf("Rebased %s in memory: %d commits rewritten.", m.branch, msg.rewritten)
+		}
+		return m, tea.Quit
+	case errors.Is(msg.err, commands.ErrDirtyTree):
+		// Local changes to the files the rebase changes may be in the way;
+		// afterStart explains the error if they aren't.
+		return m, m.prepareRebase(false, false, msg.err)
+	case !errors.Is(msg.err, commands.ErrNeedsStop):
+		m.status = errorStatus("", msg.err)
+		return m, nil
+	}
+	// A conflict: let git stop at it. The backup is already taken.
+	m.status = ""
+	return m, m.prepareRebase(false, false, msg.err)
+}
+
+// rebaseOptions returns the options the plan is rebased with.
+func (m model) rebaseOptions() commands.RebaseOptions {
+	return commands.RebaseOptions{Base: m.base.Hash, RebaseMerges: m.rebaseMerges, UpdateRefs: m.updateRefs, Autostash: m.autostash, Sign: m.sign}
+}
+
+// interactiveRebase runs git rebase -i with the plan attached to the
+// terminal.
+func (m *model) interactiveRebase(opts commands.RebaseOptions) tea.Cmd {
+	cmd, cleanup, err := commands.InteractiveRebase(opts, m.actions)
+	if err != nil {
+		m.status = lipgloss.NewStyle().Foreground(theme.Red).Render(err.Error())
+		return nil
+	}
+	return execRebase(cmd, "-i", cleanup)
+}
+
+// execRebase hands the terminal to a git rebase command and reports back
+// with a rebaseStepMsg. git's output is kept so a failure can be shown in
+// the status line once the TUI is back.
+func execRebase(cmd *exec.Cmd, flag string, cleanup func()) tea.Cmd {
+	var out bytes.Buffer
+	cmd.Stdout = io.MultiWriter(os.Stdout, &out)
+	cmd.Stderr = io.MultiWriter(os.Stderr, &out)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		if cleanup != nil {
+			cleanup()
+		}
+		return rebaseStepMsg{flag: flag, output: out.String(), err: err}
+	})
+}
+
+// stepStateMsg carries the rebase state read once a git rebase command
+// returned.
+type stepStateMsg struct {
+	step  rebaseStepMsg
+	state *commands.RebaseState
+	err   error
+}
+
+// afterRebaseStep reloads the rebase state in the background once git
+// returns.
+func (m model) afterRebaseStep(msg rebaseStepMsg) (tea.Model, tea.Cmd) {
+	ctx := m.ctx
+	m.busy = true
+	return m, func() tea.Msg {
+		s, err := commands.InProgressRebase(ctx)
+		return stepStateMsg{step: msg, state: s, err: err}
+	}
+}
+
+// applyStepState shows where the rebase stopped, quitting when it is over.
+func (m model) applyStepState(sm stepStateMsg) (tea.Model, tea.Cmd) {
+	m.busy = false
+	msg, s := sm.step, sm.state
+	if sm.err != nil {
+		m.status = lipgloss.NewStyle().Foreground(theme.Red).Render(sm.err.Error())
+		return m, nil
+	}
+	if s == nil && msg.err != nil {
+		// git refused to start, e.g. because of local changes.
+		if kind := commands.Classify(msg.output); kind != nil {
+			m.status = errorStatus("", kind)
+		} else {
+			m.status = lipgloss.NewStyle().Foreground(theme.Red).
+				Render("git rebase " + msg.flag + ": " + lastLine(msg.output, msg.err))
+		}
+		return m, nil
+	}
+	if s == nil {
+		m.exitMessage = "Rebase finished."
+		if msg.flag == "--abort" {
+			m.exitMessage = "Rebase aborted."
+		}
+		return m, tea.Quit
+	}
+	cmd := m.loadInProgress(s)
+	if msg.err != nil {
+		// Shown unless conflicts turn up, which say more.
+		m.status = lipgloss.NewStyle().Foreground(theme.Red).
+			Render("git rebase " + msg.flag + ": " + lastLine(msg.output, msg.err))
+	}
+	return m, cmd
+}
+
+// lastLine picks the line of git's output worth showing in the status line:
+// the last error, or else the last line that isn't a hint; err's text when
+// git printed nothing.
+func lastLine(out string, err error) string {
+	lines := strings.Split(out, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if l := strings.TrimSpace(lines[i]); strings.HasPrefix(l, "error: ") || strings.HasPrefix(l, "fatal: ") {
+			return l
+		}
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		if l := strings.TrimSpace(lines[i]); l != "" && !strings.HasPrefix(l, "hint:") {
+			return l
+		}
+	}
+	return err.Error()
+}
+
+// firstDone returns the index of the first done row, or len(items).
+func firstDone(items []list.Item) int {
+	for i, it := range items {
+		if _, ok := it.(doneItem); ok {
+			return i
+		}
+	}
+	return len(items)
+}
+
+// insertIndex returns where a new row goes for the current selection: above
+// it, but never among the done rows of a rebase in progress.
+func (m model) insertIndex() int {
+	return min(max(0, m.list.Index()), firstDone(m.list.Items()))
+}
+
+// branchRef returns a head-name from git's rebase state as a branch ref, or
+// "" when the rebase started from a detached HEAD.
+func branchRef(headName string) string {
+	if strings.HasPrefix(headName, "refs/") {
+		return headName
+	}
+	return ""
+}
+
+// shortHash abbreviates a full hash for display.
+func shortHash(h string) string {
+	if len(h) > 7 {
+		return h[:7]
+	}
+	return h
+}
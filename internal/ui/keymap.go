@@ -10,11 +10,25 @@ type keymap struct {
 	MoveDown   key.Binding
 	OpenAction key.Binding
 	Pick       key.Binding
+	Reword     key.Binding
 	Squash     key.Binding
 	Fixup      key.Binding
+	FixupUse   key.Binding
+	FixupEdit  key.Binding
 	Edit       key.Binding
 	Drop       key.Binding
+	InsertExec key.Binding
+	ExecAll    key.Binding
+	Break      key.Binding
+	UpdateRefs key.Binding
+	Autosquash key.Binding
+	Sign       key.Binding
+	Undo       key.Binding
 	Rebase     key.Binding
+	StopView   key.Binding
+	Continue   key.Binding
+	Skip       key.Binding
+	Abort      key.Binding
 	Quit       key.Binding
 }
 
@@ -23,10 +37,24 @@ var keys = keymap{
 	MoveDown:   key.NewBinding(key.WithKeys("ctrl+down"), key.WithHelp("ctrl+↓", "move down")),
 	OpenAction: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "set action")),
 	Pick:       key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "pick")),
+	Reword:     key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "reword")),
 	Squash:     key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "squash")),
 	Fixup:      key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "fixup")),
+	FixupUse:   key.NewBinding(key.WithKeys("F"), key.WithHelp("F", "fixup -C")),
+	FixupEdit:  key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "fixup -c")),
 	Edit:       key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit")),
 	Drop:       key.NewBinding(key.WithKeys("x", "d"), key.WithHelp("x/d", "drop")),
+	InsertExec: key.NewBinding(key.WithKeys("i"), key.WithHelp("i", "insert exec")),
+	ExecAll:    key.NewBinding(key.WithKeys("I"), key.WithHelp("I", "exec after each")),
+	Break:      key.NewBinding(key.WithKeys("b"), key.WithHelp("b", "insert break")),
+	UpdateRefs: key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "toggle update-refs")),
+	Autosquash: key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "toggle autosquash")),
+	Sign:       key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "toggle signing")),
+	Undo:       key.NewBinding(key.WithKeys("U"), key.WithHelp("U", "undo a rebase")),
 	Rebase:     key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "start rebase")),
+	StopView:   key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "stop details")),
+	Continue:   key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "continue")),
+	Skip:       key.NewBinding(key.WithKeys("ctrl+k"), key.WithHelp("ctrl+k", "skip")),
+	Abort:      key.NewBinding(key.WithKeys("ctrl+a"), key.WithHelp("ctrl+a", "abort")),
 	Quit:       key.NewBinding(key.WithKeys("ctrl+c", "q"), key.WithHelp("ctrl+c/q", "quit")),
 }
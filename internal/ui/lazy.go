@@ -0,0 +1,172 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	list "github.com/charmbracelet/bubbles/v2/list"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/ui/theme"
+)
+
+// loadAhead is how close to the end of the list the cursor gets before the
+// next page of older commits is loaded.
+const loadAhead = 5
+
+// pageMsg carries a page of older commits. For a HEAD~n window, base and
+// window are the grown window the commits complete.
+type pageMsg struct {
+	commits []commands.Commit
+	base    commands.Base
+	window  int
+	err     error
+}
+
+// loadSpinner returns the spinner's tick when background loading starts.
+func (m *model) loadSpinner() tea.Cmd {
+	if m.loadingPlan {
+		// It is already turning.
+		return nil
+	}
+	return m.spinner.Tick
+}
+
+// pickItems turns commits into pick rows.
+func pickItems(commits []commands.Commit) []list.Item {
+	items := make([]list.Item, 0, len(commits))
+	for _, c := range commits {
+		items = append(items, commitItem{Commit: c, Act: pick})
+	}
+	return items
+}
+
+// hasMore reports whether older commits can still be loaded.
+func (m model) hasMore() bool {
+	return m.stream != nil || m.window > 0
+}
+
+// repoLabel names the repository and branch being rebased, e.g.
+// "~/src/app (main)", with the home directory shortened to ~.
+func (m model) repoLabel(branch string) string {
+	if branch == "" {
+		branch = "detached HEAD"
+	}
+	if m.repo == "" {
+		return branch
+	}
+	dir := m.repo
+	if home, err := os.UserHomeDir(); err == nil && home != "/" {
+		if rel, err := filepath.Rel(home, dir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			dir = filepath.Join("~", rel)
+		}
+	}
+	return dir + " (" + branch + ")"
+}
+
+// planTitle names the repository, the branch and what it rebases onto.
+func (m model) planTitle() string {
+	t := m.repoLabel(m.branch)
+	if m.base.Ref != "" {
+		t += " onto " + m.base.Ref
+	}
+	if m.stream != nil {
+		t += fmt.Sprintf(" (%d loaded, more below)", len(m.list.Items()))
+	}
+	if m.sign {
+		t += " • signing"
+	}
+	if m.loading {
+		t += " " + m.spinner.View()
+	}
+	return t
+}
+
+// loadMore reads the next page in the background once the cursor nears
+// the end of the list. A range being streamed continues where it stopped;
+// a HEAD~n window grows by a page.
+func (m *model) loadMore() tea.Cmd {
+	if m.loading || !m.hasMore() || m.inProgress != nil || m.todoPath != "" || m.rebaseMerges {
+		return nil
+	}
+	if m.list.Index() < len(m.list.Items())-loadAhead {
+		return nil
+	}
+	return m.loadPage(m.pageSize)
+}
+
+// loadPage reads up to n older commits in the background, or all that are
+// left of a streamed range when n < 0.
+func (m *model) loadPage(n int) tea.Cmd {
+	spin := m.loadSpinner()
+	m.loading = true
+	m.list.Title = m.planTitle()
+	if s := m.stream; s != nil {
+		return tea.Batch(spin, func() tea.Msg {
+			commits, err := s.Next(n)
+			return pageMsg{commits: commits, err: err}
+		})
+	}
+	ctx, old, window := m.ctx, m.base, m.window+n
+	return tea.Batch(spin, func() tea.Msg {
+		base, err := commands.WindowBase(ctx, window)
+		if err != nil {
+			return pageMsg{err: err}
+		}
+		s, err := commands.StreamRange(ctx, base.Hash, old.Hash)
+		if err != nil {
+			return pageMsg{err: err}
+		}
+		commits, err := s.Next(-1)
+		return pageMsg{commits: commits, base: base, window: window, err: err}
+	})
+}
+
+// appendPage adds a page of older commits below the list, and starts the
+// rebase that was waiting for the rest of the range.
+func (m *model) appendPage(msg pageMsg) tea.Cmd {
+	m.loading = false
+	rebase := m.rebaseWhenLoaded
+	m.rebaseWhenLoaded = false
+	if msg.err != nil {
+		m.status = lipgloss.NewStyle().Foreground(theme.Red).Render("Couldn't load older commits: " + msg.err.Error())
+		m.stream, m.window = nil, 0
+		m.list.Title = m.planTitle()
+		return nil
+	}
+	if m.stream != nil && m.stream.Done() {
+		m.stream = nil
+	}
+	if msg.window > 0 {
+		m.base, m.window = msg.base, msg.window
+		if msg.base.Hash == "" {
+			// The window reached the root commit.
+			m.window = 0
+		}
+	}
+	m.appendItems(pickItems(msg.commits))
+	if rebase {
+		m.status = ""
+		return m.rebase()
+	}
+	return nil
+}
+
+// appendItems adds older rows below the list. With autosquash on, the
+// fixups on the new rows, and those whose targets they hold, are arranged.
+func (m *model) appendItems(items []list.Item) {
+	idx := m.list.Index()
+	m.list.SetItems(append(m.list.Items(), items...))
+	if m.autosquash {
+		m.arrange()
+	}
+	if m.updateRefs {
+		m.setUpdateRefs(true)
+	}
+	m.list.Select(idx)
+	m.list.Title = m.planTitle()
+}
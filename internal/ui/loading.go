@@ -0,0 +1,160 @@
+package ui
+
+import (
+	"context"
+	"errors"
+
+	list "github.com/charmbracelet/bubbles/v2/list"
+	spinner "github.com/charmbracelet/bubbles/v2/spinner"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+	"github.com/fredrikmwold/rebasei-tui/internal/ui/theme"
+)
+
+// planMsg carries what loadPlan found: a rebase in progress, or the plan
+// for a new one.
+type planMsg struct {
+	progress   *commands.RebaseState
+	items      []list.Item
+	base       commands.Base
+	stream     *commands.CommitStream
+	window     int
+	repo       string
+	branch     string
+	updateRefs bool
+	sign       bool
+	err        error
+}
+
+// loadPlan looks for a rebase in progress and otherwise lists the commits
+// to rebase: the range since the base, its first page when it is read as
+// a stream, or the last count commits when there is no upstream.
+func loadPlan(ctx context.Context, opts Options, count int) tea.Cmd {
+	return func() tea.Msg {
+		if err := commands.CheckRepo(ctx); err != nil {
+			return planMsg{err: err}
+		}
+		msg := planMsg{repo: commands.RepoDir(ctx), branch: commands.CurrentBranch(ctx)}
+		// A rebase that git stopped in the middle of comes before a new one.
+		if msg.progress, _ = commands.InProgressRebase(ctx); msg.progress != nil {
+			return msg
+		}
+		msg.updateRefs = opts.UpdateRefs || commands.ConfigBool(ctx, "rebase.updateRefs")
+		msg.sign = opts.Sign || commands.ConfigBool(ctx, "commit.gpgSign")
+		base, err := commands.ResolveBase(ctx, opts.Base)
+		if err != nil && opts.Base == "" && !errors.Is(err, context.Canceled) {
+			// Without an upstream, take the last commits along HEAD's first-parent chain.
+			base, err = commands.WindowBase(ctx, count)
+			if err == nil && base.Hash != "" {
+				msg.window = count
+			}
+		}
+		msg.base = base
+		if err != nil {
+			msg.err = err
+			return msg
+		}
+		switch {
+		case opts.RebaseMerges:
+			var plan []commands.CommitAction
+			plan, msg.err = commands.MergesPlan(ctx, base.Hash)
+			msg.items = planItems(plan)
+		case msg.window > 0:
+			var commits []commands.Commit
+			commits, msg.err = commands.ListRange(ctx, base.Hash)
+			msg.items = pickItems(commits)
+		default:
+			// A range can be long; read a page now and the rest on demand.
+			s, err := commands.StreamRange(ctx, base.Hash, "HEAD")
+			if err != nil {
+				msg.err = err
+				return msg
+			}
+			commits, err := s.Next(count)
+			msg.items, msg.err = pickItems(commits), err
+			if !s.Done() {
+				msg.stream = s
+			}
+			if err == nil && len(commits) == 0 {
+				msg.err = errors.New("no commits between base and HEAD; nothing to rebase")
+			}
+		}
+		return msg
+	}
+}
+
+// applyPlan shows what loadPlan found, or why there is nothing to rebase.
+func (m *model) applyPlan(msg planMsg) tea.Cmd {
+	m.loadingPlan = false
+	m.repo, m.branch = msg.repo, msg.branch
+	if msg.progress != nil {
+		cmd := m.loadInProgress(msg.progress)
+		// git already added update-ref rows if it was asked to.
+		for _, it := range m.list.Items() {
+			if ii, ok := it.(instructionItem); ok && ii.Ins.Command == todo.UpdateRef {
+				m.updateRefs = true
+			}
+		}
+		return cmd
+	}
+	m.base, m.stream, m.window = msg.base, msg.stream, msg.window
+	m.sign = msg.sign
+	m.list.SetItems(msg.items)
+	m.list.Title = m.planTitle()
+	if msg.err != nil {
+		if _, ok := explain(msg.err); ok {
+			m.loadErr = msg.err
+			return nil
+		}
+		switch {
+		case errors.Is(msg.err, commands.ErrShallow):
+			m.status = "The " + msg.err.Error()
+		case m.opts.Base != "" && m.base.Ref == "":
+			// The base the user asked for doesn't resolve.
+			m.status = lipgloss.NewStyle().Foreground(theme.Red).Render(msg.err.Error())
+		case m.base.Ref != "":
+			m.status = "No commits since " + m.base.Ref + "; nothing to rebase."
+		default:
+			m.status = errorStatus("Couldn't list the commits: ", msg.err)
+		}
+		return nil
+	}
+	if msg.updateRefs {
+		m.setUpdateRefs(true)
+	}
+	if !m.rebaseMerges {
+		m.predictor = commands.NewPredictor()
+		m.setAutosquash(true)
+		if m.arranged == nil {
+			// Nothing to arrange; don't greet the user with a status line.
+			m.status = ""
+		}
+	}
+	if m.branch == "" && m.status == "" {
+		m.status = errorStatus("", commands.ErrDetachedHead)
+	}
+	return nil
+}
+
+// updateSpinner keeps the spinner turning while git works in the
+// background, and lets it stop once nothing is loading.
+func (m model) updateSpinner(msg spinner.TickMsg) (tea.Model, tea.Cmd) {
+	if !m.loadingPlan && !m.loading {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.spinner, cmd = m.spinner.Update(msg)
+	if m.loading {
+		m.list.Title = m.planTitle()
+	}
+	return m, cmd
+}
+
+// renderLoading fills the list area while the plan is being loaded.
+func (m model) renderLoading() string {
+	text := m.spinner.View() + " " + lipgloss.NewStyle().Foreground(theme.Subtext0).Render("Loading commits… (q to cancel)")
+	return lipgloss.Place(m.innerWidth, m.innerHeight, lipgloss.Center, lipgloss.Center, text)
+}
@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	textarea "github.com/charmbracelet/bubbles/v2/textarea"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/ui/theme"
+)
+
+// openMessageEditor opens the commit message editor for the selected commit,
+// prefilled with its edited message or the full original message.
+func (m *model) openMessageEditor() tea.Cmd {
+	idx := m.list.Index()
+	if idx < 0 {
+		return nil
+	}
+	ci, ok := m.list.Items()[idx].(commitItem)
+	if !ok {
+		return nil
+	}
+	if ci.Message != "" {
+		return m.openEditor(idx, ci.Message, false)
+	}
+	ctx := m.ctx
+	m.busy = true
+	return func() tea.Msg {
+		msg, err := commands.CommitMessage(ctx, ci.Commit.Hash)
+		return messageMsg{idx: idx, msg: msg, err: err}
+	}
+}
+
+// messageMsg carries the original message of the commit at idx.
+type messageMsg struct {
+	idx int
+	msg string
+	err error
+}
+
+// applyMessage opens the editor on the message openMessageEditor loaded.
+func (m model) applyMessage(msg messageMsg) (tea.Model, tea.Cmd) {
+	m.busy = false
+	if msg.err != nil {
+		m.status = lipgloss.NewStyle().Foreground(theme.Red).Render("Couldn't load commit message: " + msg.err.Error())
+		return m, nil
+	}
+	return m, m.openEditor(msg.idx, msg.msg, false)
+}
+
+// openEditor opens the message editor on msg for the row at idx; composing
+// is set when it edits the message of a squash group before the rebase.
+func (m *model) openEditor(idx int, msg string, composing bool) tea.Cmd {
+	ta := textarea.New()
+	ta.ShowLineNumbers = false
+	ta.Prompt = ""
+	ta.MaxHeight = 0
+	ta.SetWidth(m.editorWidth())
+	ta.SetHeight(m.editorHeight())
+	ta.SetValue(msg)
+	m.editor = ta
+	m.editorIdx = idx
+	m.editorOpen = true
+	m.composing = composing
+	return m.editor.Focus()
+}
+
+// editorWidth returns the textarea width for the current terminal size.
+func (m model) editorWidth() int {
+	return max(20, min(80, m.innerWidth-6))
+}
+
+// editorHeight returns the textarea height for the current terminal size.
+func (m model) editorHeight() int {
+	return max(3, min(16, m.innerHeight-8))
+}
+
+// updateMessageEditor handles input while the message editor is open.
+func (m model) updateMessageEditor(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if km, ok := msg.(tea.KeyMsg); ok {
+		switch km.String() {
+		case "ctrl+s":
+			text := strings.TrimSpace(m.editor.Value())
+			if text == "" {
+				m.status = lipgloss.NewStyle().Foreground(theme.Red).Render("Commit message can't be empty.")
+				return m, nil
+			}
+			m.editorOpen = false
+			if m.composing {
+				return m, m.saveComposed(text)
+			}
+			items := m.list.Items()
+			if ci, ok := items[m.editorIdx].(commitItem); ok {
+				ci.Act = reword
+				ci.Message = text
+				return m, m.list.SetItem(m.editorIdx, ci)
+			}
+			return m, nil
+		case "esc":
+			m.editorOpen = false
+			if m.composing {
+				m.status = "Rebase not started; the squash message is asked for again on ctrl+r."
+			}
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.editor, cmd = m.editor.Update(msg)
+	return m, cmd
+}
+
+// renderMessageEditor renders the message editor inside a bordered box.
+func (m model) renderMessageEditor() string {
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		Padding(0, 1).
+		Foreground(theme.Text).
+		BorderForeground(theme.Mauve)
+	title := lipgloss.NewStyle().Foreground(theme.Blue).Bold(true).Render("Reword commit")
+	hint := lipgloss.NewStyle().Foreground(theme.Subtext0).Render("ctrl+s save • esc cancel")
+	if m.composing {
+		title = lipgloss.NewStyle().Foreground(theme.Blue).Bold(true).
+			Render(fmt.Sprintf("Message for the %d squashed commits", m.composeCount))
+		hint = lipgloss.NewStyle().Foreground(theme.Subtext0).Render("ctrl+s save and rebase • esc back to the plan")
+	}
+	return box.Render(title + "\n\n" + m.editor.View() + "\n\n" + hint)
+}
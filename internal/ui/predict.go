@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/ui/theme"
+)
+
+// predictDelay lets a burst of moves settle before the plan is simulated.
+const predictDelay = 250 * time.Millisecond
+
+// predictTickMsg asks for a prediction of the plan as of generation gen.
+type predictTickMsg struct{ gen int }
+
+// predictionMsg carries the predicted conflicts per commit hash for the plan
+// as of generation gen.
+type predictionMsg struct {
+	gen       int
+	conflicts map[string][]string
+	err       error
+}
+
+// planSignature identifies what the prediction depends on: which commits
+// are applied, in which order.
+func (m model) planSignature() string {
+	var b strings.Builder
+	for _, it := range m.list.Items() {
+		if ci, ok := it.(commitItem); ok {
+			b.WriteString(ci.Commit.Hash)
+			if ci.Act == drop {
+				b.WriteString("-")
+			}
+			b.WriteString(" ")
+		}
+	}
+	return b.String()
+}
+
+// schedulePrediction starts a new prediction generation when the plan
+// changed since the last one. The previous generation is cancelled, and
+// dropped if it still reports back.
+func (m *model) schedulePrediction() tea.Cmd {
+	// Until the whole range is loaded the oldest commits are missing.
+	if m.predictor == nil || m.stream != nil {
+		return nil
+	}
+	sig := m.planSignature()
+	if sig == m.predictSig {
+		return nil
+	}
+	m.predictSig = sig
+	if m.predictCancel != nil {
+		m.predictCancel()
+	}
+	m.predictCtx, m.predictCancel = context.WithCancel(m.ctx)
+	m.predictGen++
+	gen := m.predictGen
+	return tea.Tick(predictDelay, func(time.Time) tea.Msg { return predictTickMsg{gen: gen} })
+}
+
+// predict simulates the current plan in the background.
+func (m model) predict(gen int) tea.Cmd {
+	ctx, p, base, plan := m.predictCtx, m.predictor, m.base.Hash, m.collectActions()
+	return func() tea.Msg {
+		conflicts, err := p.Predict(ctx, base, plan)
+		return predictionMsg{gen: gen, conflicts: conflicts, err: err}
+	}
+}
+
+// applyPrediction marks the rows that are predicted to conflict.
+func (m *model) applyPrediction(msg predictionMsg) {
+	if msg.err != nil {
+		m.status = lipgloss.NewStyle().Foreground(theme.Red).Render("Conflict prediction failed: " + msg.err.Error())
+		return
+	}
+	items := m.list.Items()
+	for i, it := range items {
+		if ci, ok := it.(commitItem); ok {
+			ci.Conflicts = msg.conflicts[ci.Commit.Hash]
+			items[i] = ci
+		}
+	}
+	m.list.SetItems(items)
+}
@@ -0,0 +1,167 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	list "github.com/charmbracelet/bubbles/v2/list"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+	"github.com/fredrikmwold/rebasei-tui/internal/ui/theme"
+)
+
+// squashGroups returns the rows of each squash group in the list: a commit
+// and the squash and fixup rows melded into it, as indexes oldest first.
+// Dropped commits and update-ref rows are skipped over; any other row ends
+// a group.
+func squashGroups(items []list.Item) [][]int {
+	var groups [][]int
+	var cur []int
+	flush := func() {
+		if len(cur) > 1 {
+			groups = append(groups, cur)
+		}
+		cur = nil
+	}
+	for i := len(items) - 1; i >= 0; i-- {
+		switch it := items[i].(type) {
+		case commitItem:
+			switch {
+			case it.Act == drop:
+			case it.Act.melds():
+				if cur != nil {
+					cur = append(cur, i)
+				}
+			default:
+				flush()
+				cur = []int{i}
+			}
+		case instructionItem:
+			if it.Ins.Command != todo.UpdateRef {
+				flush()
+			}
+		}
+	}
+	flush()
+	return groups
+}
+
+// groupKey identifies a group's commits and actions, so a composed message
+// is asked for again once the group changes.
+func groupKey(items []list.Item, group []int) string {
+	var b strings.Builder
+	for _, i := range group {
+		ci := items[i].(commitItem)
+		fmt.Fprintf(&b, "%s %s\n", ci.Act, ci.Commit.Hash)
+	}
+	return b.String()
+}
+
+// composeSquash looks in the background for the next squash group whose
+// message hasn't been composed yet, to open the message editor prefilled
+// with the messages of the group and their merged trailers. Groups of
+// fixups only are left to git unless their trailers would be lost. It
+// returns nil when there is no group to look at.
+func (m *model) composeSquash() tea.Cmd {
+	items := m.list.Items()
+	groups := squashGroups(items)
+	// A message composed for a group the row no longer ends is stale.
+	newest := map[int]bool{}
+	for _, g := range groups {
+		newest[g[len(g)-1]] = true
+	}
+	for i, it := range items {
+		if ci, ok := it.(commitItem); ok && ci.Act.melds() && ci.Message != "" && !newest[i] {
+			ci.Message, ci.composedFor = "", ""
+			m.list.SetItem(i, ci)
+		}
+	}
+	var pending []squashGroup
+	for _, g := range groups {
+		lastIdx := g[len(g)-1]
+		last := items[lastIdx].(commitItem)
+		key := groupKey(items, g)
+		if last.Message != "" && last.composedFor == key {
+			continue
+		}
+		sg := squashGroup{idx: lastIdx, key: key, plan: make([]commands.CommitAction, len(g))}
+		for j, i := range g {
+			ci := items[i].(commitItem)
+			sg.plan[j] = commands.CommitAction{Commit: ci.Commit, Instruction: ci.instruction(), Message: ci.Message}
+			if j > 0 && (ci.Act == squash || ci.Act == fixupEdit) {
+				sg.squashes = true
+			}
+		}
+		pending = append(pending, sg)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	ctx := m.ctx
+	m.busy = true
+	m.status = "Composing the squash messages…"
+	return func() tea.Msg {
+		rules := commands.TrailerRules(ctx)
+		for _, sg := range pending {
+			msg, merged, err := commands.ComposeSquash(ctx, rules, sg.plan)
+			if err != nil {
+				return composedMsg{err: err}
+			}
+			if sg.squashes || merged {
+				return composedMsg{group: &sg, msg: msg}
+			}
+		}
+		return composedMsg{}
+	}
+}
+
+// squashGroup is a group composeSquash composes a message for: idx is its
+// newest row and key its groupKey; squashes is set when it has rows whose
+// message git would ask for.
+type squashGroup struct {
+	idx      int
+	key      string
+	plan     []commands.CommitAction
+	squashes bool
+}
+
+// composedMsg carries the composed message of the next group to edit, or
+// no group when the rest can be left to git.
+type composedMsg struct {
+	group *squashGroup
+	msg   string
+	err   error
+}
+
+// applyComposed opens the editor on the composed message, or carries on
+// with the rebase when no group needs one.
+func (m model) applyComposed(msg composedMsg) (tea.Model, tea.Cmd) {
+	m.busy = false
+	m.status = ""
+	switch {
+	case msg.err != nil:
+		m.status = lipgloss.NewStyle().Foreground(theme.Red).Render("Couldn't compose the squash message: " + msg.err.Error())
+		return m, nil
+	case msg.group == nil:
+		return m, m.runPlan()
+	}
+	m.composeKey = msg.group.key
+	m.composeCount = len(msg.group.plan)
+	return m, m.openEditor(msg.group.idx, msg.msg, true)
+}
+
+// saveComposed keeps the composed message on the group's newest row and
+// carries on with the rebase.
+func (m *model) saveComposed(text string) tea.Cmd {
+	items := m.list.Items()
+	ci, ok := items[m.editorIdx].(commitItem)
+	if !ok {
+		return nil
+	}
+	ci.Message = text
+	ci.composedFor = m.composeKey
+	return tea.Batch(m.list.SetItem(m.editorIdx, ci), m.rebase())
+}
@@ -0,0 +1,154 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	key "github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+	"github.com/fredrikmwold/rebasei-tui/internal/ui/theme"
+)
+
+// stopMsg carries what the stop view shows: HEAD and the working tree.
+// changed is set when a key changed them first; changeErr is how that
+// failed.
+type stopMsg struct {
+	head      commands.Commit
+	lines     []string
+	err       error
+	changed   bool
+	changeErr error
+}
+
+// loadStop refreshes what the stop view shows in the background, after
+// running change when it isn't nil.
+func (m *model) loadStop(change func(context.Context) error) tea.Cmd {
+	ctx := m.ctx
+	return func() tea.Msg {
+		var msg stopMsg
+		if change != nil {
+			msg.changed = true
+			msg.changeErr = change(ctx)
+		}
+		if msg.head, msg.err = commands.HeadCommit(ctx); msg.err == nil {
+			msg.lines, msg.err = commands.ShortStatus(ctx)
+		}
+		return msg
+	}
+}
+
+// applyStop shows what loadStop read.
+func (m *model) applyStop(msg stopMsg) {
+	if msg.changed {
+		m.rewriting = false
+	}
+	if m.inProgress == nil {
+		return
+	}
+	m.stopHead, m.stopStatusLines = msg.head, msg.lines
+	switch {
+	case msg.changeErr != nil:
+		m.status = lipgloss.NewStyle().Foreground(theme.Red).Render(msg.changeErr.Error())
+	case msg.err != nil:
+		m.status = lipgloss.NewStyle().Foreground(theme.Red).Render(msg.err.Error())
+	case msg.changed:
+		m.status = m.stopStatus()
+	}
+}
+
+// stopTitle says why git stopped.
+func (m model) stopTitle() string {
+	s := m.inProgress
+	if len(s.Done) == 0 {
+		return "Stopped"
+	}
+	d := doneItem{Ins: s.Done[0].Instruction, Commit: s.Done[0].Commit}
+	switch d.Ins.Command {
+	case todo.Edit:
+		return "Stopped to edit " + d.Commit.HashShort + " " + d.Commit.Subject
+	case todo.Break:
+		return "Paused at a break"
+	case todo.Exec:
+		return "Stopped after exec " + d.Ins.Arg
+	}
+	return "Stopped at " + d.text()
+}
+
+// updateStopView handles keys while the stop view is shown.
+func (m model) updateStopView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var change func(context.Context) error
+	switch {
+	case key.Matches(msg, keys.StopView), msg.String() == "esc":
+		m.stopOpen = false
+		return m, nil
+	case msg.String() == "a":
+		change = commands.StageAll
+	case msg.String() == "c":
+		change = commands.AmendStaged
+	case msg.String() == "r":
+		change = commands.ResetToParent
+	case msg.String() == "!":
+		// The shell may well finish the rebase itself; reload it afterwards.
+		return m, tea.ExecProcess(commands.ShellCmd(m.repo), func(error) tea.Msg {
+			return rebaseStepMsg{}
+		})
+	default:
+		return m, nil
+	}
+	m.rewriting = true
+	return m, m.loadStop(change)
+}
+
+// renderStopView renders where git stopped, HEAD, the working tree status
+// and the keys that help before continuing.
+func (m model) renderStopView() string {
+	w, h := m.innerWidth, m.innerHeight
+	dim := lipgloss.NewStyle().Foreground(theme.Subtext0)
+	title := lipgloss.NewStyle().Bold(true).Foreground(theme.Blue).Render(m.stopTitle())
+	lines := []string{" " + title}
+	head := m.stopHead
+	lines = append(lines, fmt.Sprintf(" %s %s %s", dim.Render("HEAD:"), head.HashShort, head.Subject))
+	lines = append(lines, fmt.Sprintf(" %s %s  %s %s", dim.Render("Author:"), head.Author, dim.Render("Date:"), head.Date))
+	lines = append(lines, lipgloss.NewStyle().Foreground(theme.Surface2).Render(strings.Repeat("─", w)))
+	if len(m.stopStatusLines) == 0 {
+		lines = append(lines, dim.Render(" Working tree clean"))
+	} else {
+		lines = append(lines, dim.Render(" Working tree"))
+		// Keep the help line visible when the status is long.
+		room := max(1, h-len(lines)-1)
+		for i, l := range m.stopStatusLines {
+			if i == room-1 && len(m.stopStatusLines) > room {
+				lines = append(lines, dim.Render(fmt.Sprintf("  … %d more", len(m.stopStatusLines)-i)))
+				break
+			}
+			lines = append(lines, "  "+colorStatusLine(l))
+		}
+	}
+	for len(lines) < h-1 {
+		lines = append(lines, "")
+	}
+	help := "a stage all • c amend staged • r reset HEAD~ (split) • ! shell • tab plan • ctrl+r continue • ctrl+k skip • ctrl+a abort • q quit"
+	lines = append(lines[:max(0, h-1)], lipgloss.NewStyle().Foreground(theme.Surface2).Render(truncateToWidth(help, w)))
+	return lipgloss.NewStyle().MaxWidth(w).Render(strings.Join(lines, "\n"))
+}
+
+// colorStatusLine colors a "git status --short" line: staged changes green,
+// unstaged red, like git does.
+func colorStatusLine(l string) string {
+	if len(l) < 3 {
+		return l
+	}
+	staged, unstaged := l[:1], l[1:2]
+	if l[:2] == "??" {
+		return lipgloss.NewStyle().Foreground(theme.Red).Render(l[:2]) +
+			lipgloss.NewStyle().Foreground(theme.Text).Render(l[2:])
+	}
+	return lipgloss.NewStyle().Foreground(theme.Green).Render(staged) +
+		lipgloss.NewStyle().Foreground(theme.Red).Render(unstaged) +
+		lipgloss.NewStyle().Foreground(theme.Text).Render(l[2:])
+}
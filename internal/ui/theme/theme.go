@@ -21,8 +21,11 @@ var (
 	Peach = lipgloss.Color("#fab387")
 	Red   = lipgloss.Color("#f38ba8")
 	// Additional accents to reduce clashes
-	Sky    = lipgloss.Color("#89dceb")
-	Yellow = lipgloss.Color("#f9e2af")
+	Sky      = lipgloss.Color("#89dceb")
+	Yellow   = lipgloss.Color("#f9e2af")
+	Maroon   = lipgloss.Color("#eba0ac")
+	Flamingo = lipgloss.Color("#f2cdcd")
+	Teal     = lipgloss.Color("#94e2d5")
 )
 
 // Convenience
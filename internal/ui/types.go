@@ -1,14 +1,24 @@
 package ui
 
+import (
+	"strings"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
 // action represents a rebase action for a commit.
 type action string
 
 const (
-	pick   action = "pick"
-	squash action = "squash"
-	fixup  action = "fixup"
-	edit   action = "edit"
-	drop   action = "drop"
+	pick   action = action(todo.Pick)
+	reword action = action(todo.Reword)
+	squash action = action(todo.Squash)
+	fixup  action = action(todo.Fixup)
+	// fixup variants that take the message from the fixup commit
+	fixupUse  action = action(todo.Fixup) + " -C"
+	fixupEdit action = action(todo.Fixup) + " -c"
+	edit      action = action(todo.Edit)
+	drop      action = action(todo.Drop)
 )
 
 // actionDescription returns a short explanation for each rebase action.
@@ -16,10 +26,16 @@ func actionDescription(a action) string {
 	switch a {
 	case pick:
 		return "use commit as-is"
+	case reword:
+		return "use commit; edit its message here"
 	case squash:
 		return "combine into previous commit; edit combined message"
 	case fixup:
 		return "combine into previous commit; keep previous message"
+	case fixupUse:
+		return "combine into previous commit; use this commit's message"
+	case fixupEdit:
+		return "like fixup -C, but edit the message"
 	case edit:
 		return "pause to edit this commit during rebase"
 	case drop:
@@ -28,3 +44,22 @@ func actionDescription(a action) string {
 		return ""
 	}
 }
+
+// melds reports whether a combines the commit into the previous one.
+func (a action) melds() bool {
+	return a == squash || a == fixup || a == fixupUse || a == fixupEdit
+}
+
+// command splits a into its todo command and option, e.g. "fixup -C".
+func (a action) command() (todo.Command, string) {
+	cmd, opt, _ := strings.Cut(string(a), " ")
+	return todo.Command(cmd), opt
+}
+
+// actionFor returns the action matching a todo instruction.
+func actionFor(ins todo.Instruction) action {
+	if ins.Option != "" {
+		return action(string(ins.Command) + " " + ins.Option)
+	}
+	return action(ins.Command)
+}
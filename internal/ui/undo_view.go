@@ -0,0 +1,128 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/commands"
+	"github.com/fredrikmwold/rebasei-tui/internal/ui/theme"
+)
+
+// backupsMsg carries the backups taken before earlier rebases.
+type backupsMsg struct {
+	backups []commands.Backup
+	err     error
+}
+
+// openUndo lists the backups taken before earlier rebases in the
+// background; the list opens once they are read.
+func (m *model) openUndo() tea.Cmd {
+	m.busy = true
+	return listBackups(m.ctx)
+}
+
+// listBackups reads the backups for a backupsMsg.
+func listBackups(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		bs, err := commands.ListBackups(ctx)
+		return backupsMsg{backups: bs, err: err}
+	}
+}
+
+// applyBackups opens the backup list.
+func (m model) applyBackups(msg backupsMsg) (tea.Model, tea.Cmd) {
+	m.busy = false
+	if msg.err != nil {
+		m.status = lipgloss.NewStyle().Foreground(theme.Red).Render("Couldn't list backups: " + msg.err.Error())
+		return m, nil
+	}
+	m.backups = msg.backups
+	m.undoIdx = 0
+	m.undoOpen = true
+	return m, nil
+}
+
+// updateUndo handles keys while the backup list is open.
+func (m model) updateUndo(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up":
+		m.undoIdx = max(0, m.undoIdx-1)
+	case "down":
+		m.undoIdx = max(0, min(len(m.backups)-1, m.undoIdx+1))
+	case "enter":
+		if len(m.backups) == 0 {
+			return m, nil
+		}
+		ctx, b := m.ctx, m.backups[m.undoIdx]
+		m.rewriting = true
+		m.status = "Restoring " + b.ShortBranch() + "…"
+		return m, func() tea.Msg {
+			undo, err := commands.RestoreBackup(ctx, b)
+			return restoredMsg{backup: b, undo: undo, err: err}
+		}
+	case "esc", "q":
+		m.undoOpen = false
+		if m.undoOnly {
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+// restoredMsg reports the end of restoring backup; undo is the backup of
+// the state it replaced.
+type restoredMsg struct {
+	backup commands.Backup
+	undo   commands.Backup
+	err    error
+}
+
+// afterRestore quits once the branch is restored.
+func (m model) afterRestore(msg restoredMsg) (tea.Model, tea.Cmd) {
+	m.rewriting = false
+	b := msg.backup
+	if msg.err != nil {
+		m.status = errorStatus("Couldn't restore "+b.ShortBranch()+": ", msg.err)
+		return m, nil
+	}
+	m.exitMessage = fmt.Sprintf("Restored %s to %s from %s.\nThe previous state is saved as %s.",
+		b.ShortBranch(), shortHash(b.Head), b.Ref, msg.undo.Ref)
+	return m, tea.Quit
+}
+
+// renderUndo renders the backup list inside a bordered box.
+func (m model) renderUndo() string {
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		Padding(0, 1).
+		Foreground(theme.Text).
+		BorderForeground(theme.Mauve)
+	w := max(20, min(90, m.innerWidth-6))
+	title := lipgloss.NewStyle().Foreground(theme.Blue).Bold(true).Render("Restore a branch from a backup")
+	hint := lipgloss.NewStyle().Foreground(theme.Subtext0).Render("enter restore • esc cancel")
+	if len(m.backups) == 0 {
+		body := lipgloss.NewStyle().Foreground(theme.Subtext0).Render("No backups yet; one is recorded before every rebase.")
+		return box.Render(title + "\n\n" + body + "\n\n" + hint)
+	}
+	// Each backup takes a line; keep the selection in view.
+	rows := max(1, min(len(m.backups), m.innerHeight-8))
+	first := max(0, min(m.undoIdx-rows/2, len(m.backups)-rows))
+	dim := lipgloss.NewStyle().Foreground(theme.Subtext0)
+	branch := lipgloss.NewStyle().Foreground(theme.Sky)
+	var lines []string
+	for i := first; i < first+rows; i++ {
+		b := m.backups[i]
+		line := fmt.Sprintf("%s  %s  %s  %s",
+			dim.Render(b.Time.Format("2006-01-02 15:04")), branch.Render(b.ShortBranch()), shortHash(b.Head), b.Summary)
+		prefix := "  "
+		if i == m.undoIdx {
+			prefix = lipgloss.NewStyle().Foreground(theme.Mauve).Render("> ")
+		}
+		lines = append(lines, lipgloss.NewStyle().MaxWidth(w).Render(prefix+line))
+	}
+	return box.Render(title + "\n\n" + strings.Join(lines, "\n") + "\n\n" + hint)
+}
@@ -0,0 +1,51 @@
+package ui
+
+import (
+	list "github.com/charmbracelet/bubbles/v2/list"
+
+	"github.com/fredrikmwold/rebasei-tui/internal/todo"
+)
+
+// setUpdateRefs adds or removes update-ref rows for the branches pointing at
+// the plan's commits, so that every branch of a stack follows its commit.
+// As in git, a commit's ref is updated once the fixups and squashes melded
+// into it have run. The branch being rebased is skipped; git moves it anyway.
+func (m *model) setUpdateRefs(on bool) {
+	m.updateRefs = on
+	items := m.list.Items()
+	// Work oldest first, like the todo.
+	chron := make([]list.Item, 0, len(items))
+	var refs []list.Item
+	for i := len(items) - 1; i >= 0; i-- {
+		it := items[i]
+		if ii, ok := it.(instructionItem); ok && ii.Ins.Command == todo.UpdateRef {
+			continue
+		}
+		ci, isCommit := it.(commitItem)
+		if !isCommit || !ci.Act.melds() {
+			chron = append(chron, refs...)
+			refs = nil
+		}
+		chron = append(chron, it)
+		if !isCommit || !on {
+			continue
+		}
+		for _, b := range ci.Commit.Branches {
+			if b == m.branch {
+				continue
+			}
+			refs = append(refs, instructionItem{
+				Ins:  todo.Instruction{Command: todo.UpdateRef, Arg: "refs/heads/" + b},
+				Lane: ci.Lane,
+			})
+		}
+	}
+	chron = append(chron, refs...)
+	res := make([]list.Item, 0, len(chron))
+	for i := len(chron) - 1; i >= 0; i-- {
+		res = append(res, chron[i])
+	}
+	idx := m.list.Index()
+	m.list.SetItems(res)
+	m.list.Select(min(idx, len(res)-1))
+}
@@ -0,0 +1,30 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUpdateRefsAfterFixups(t *testing.T) {
+	m := newModel(t, commit("fixup! A"), commit("B", "main"), commit("A", "stack"))
+	if !m.autosquash {
+		t.Fatal("autosquash is off after loading")
+	}
+	m = press(m, "u")
+	want := []string{"pick B", "update-ref refs/heads/stack", "fixup fixup! A", "pick A"}
+	if got := rowsOf(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows =\n%q\nwant\n%q", got, want)
+	}
+	// Once the fixup is picked again, the ref update goes right after A.
+	m.list.Select(2)
+	m = press(m, "p", "u", "u")
+	want = []string{"pick B", "pick fixup! A", "update-ref refs/heads/stack", "pick A"}
+	if got := rowsOf(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows after picking the fixup =\n%q\nwant\n%q", got, want)
+	}
+	m = press(m, "u")
+	want = []string{"pick B", "pick fixup! A", "pick A"}
+	if got := rowsOf(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows after u =\n%q\nwant\n%q", got, want)
+	}
+}
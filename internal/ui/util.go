@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"os"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss/v2"
@@ -47,3 +48,9 @@ func countLines(s string) int {
 	}
 	return 1 + strings.Count(s, "\n")
 }
+
+// isTerminal reports whether f refers to a character device such as a TTY.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -4,13 +4,15 @@
 package main
 
 import (
+	"flag"
 	"log"
 
 	"github.com/fredrikmwold/rebasei-tui/internal/ui"
 )
 
 func main() {
-	if err := ui.Run(); err != nil {
+	flag.Parse()
+	if err := ui.Run(ui.Options{Base: flag.Arg(0)}); err != nil {
 		log.Fatal(err)
 	}
 }